@@ -0,0 +1,43 @@
+package loggedio
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+)
+
+// DumpToFileAuto creates a logged I/O proxy that writes all events to a single
+// file, choosing the output format from the file name's extension:
+//
+//   - .hex: payloads as hex (see HexToWriter)
+//   - .txt: payloads as strings (see StringToWriter)
+//   - .b64: payloads as base64
+//   - .json: JSON lines (see JSONToWriter)
+//   - .bin: the raw bytes of all reads and writes, in the order they occurred,
+//     without any error or close events
+//
+// Unknown extensions produce hex. The text formats log each event on its own
+// line as "R [payload]", "W [payload]", "E [location: error]", and "C". The
+// special file names "stdout", "stderr" and "null" behave as they do in
+// DumpToFiles. The file is closed by Close() or CloseLogging().
+func DumpToFileAuto(proxiedObject interface{}, filename string) *LoggedIOProxy {
+	writer := writerForFile(filename)
+	var this *LoggedIOProxy
+	encoding := EncodingHex
+	switch strings.ToLower(filepath.Ext(filename)) {
+	case ".json":
+		this = JSONToWriter(proxiedObject, writer)
+	case ".bin":
+		this = DumpToWriters(proxiedObject, writer, writer, ioutil.Discard, "", "")
+	case ".txt":
+		encoding = EncodingString
+	case ".b64":
+		encoding = EncodingBase64
+	}
+	if this == nil {
+		this = newTextProxy(proxiedObject, nil, writer, encoding,
+			"R [%v]\n", "W [%v]\n", "E [%v: %v]\n", "C\n")
+	}
+	this.ownWriters(writer)
+	return this
+}
@@ -0,0 +1,29 @@
+package loggedio
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+)
+
+func testDumpToFileAuto(t *testing.T, name string, expected string) {
+	filename := filepath.Join(t.TempDir(), name)
+	logged := DumpToFileAuto(&MockIO{}, filename)
+	logged.Read(make([]byte, 3))
+	logged.Write([]byte("test"))
+	expectNoError(t, logged.CloseLogging())
+
+	contents, err := ioutil.ReadFile(filename)
+	expectNoError(t, err)
+	expectString(t, expected, string(contents))
+}
+
+func TestDumpToFileAuto(t *testing.T) {
+	testDumpToFileAuto(t, "dump.b64", "R [YWJj]\nW [dGVzdA==]\n")
+	testDumpToFileAuto(t, "dump.bin", "abctest")
+	testDumpToFileAuto(t, "dump.txt", "R [abc]\nW [test]\n")
+	testDumpToFileAuto(t, "dump.hex", "R [61 62 63]\nW [74 65 73 74]\n")
+	testDumpToFileAuto(t, "dump.unknown", "R [61 62 63]\nW [74 65 73 74]\n")
+	testDumpToFileAuto(t, "dump.json",
+		`{"event":"read","seq":1,"data":"YWJj"}`+"\n"+`{"event":"write","seq":2,"data":"dGVzdA=="}`+"\n")
+}
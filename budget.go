@@ -0,0 +1,41 @@
+package loggedio
+
+import (
+	"errors"
+	"fmt"
+)
+
+// ErrBudgetExceeded is returned by Read() and Write() once the proxy's
+// TotalByteBudget has been exceeded.
+var ErrBudgetExceeded = errors.New("LoggedIO: total byte budget exceeded")
+
+// isOverBudget returns true if more than TotalByteBudget bytes have been
+// transferred.
+func (this *LoggedIOProxy) isOverBudget() bool {
+	if this.TotalByteBudget <= 0 {
+		return false
+	}
+	this.mutex.Lock()
+	defer this.mutex.Unlock()
+	return this.stats.BytesRead+this.stats.BytesWritten > this.TotalByteBudget
+}
+
+// refuseOverBudget reports and returns ErrBudgetExceeded if the budget has
+// been exceeded.
+func (this *LoggedIOProxy) refuseOverBudget(location string) error {
+	if !this.isOverBudget() {
+		return nil
+	}
+	this.reportError(location, ErrBudgetExceeded)
+	return ErrBudgetExceeded
+}
+
+// checkBudget reports when the total bytes transferred goes from within the
+// budget (before) to beyond it (after).
+func (this *LoggedIOProxy) checkBudget(before, after int64) {
+	budget := this.TotalByteBudget
+	if budget > 0 && before <= budget && after > budget {
+		this.reportNotify(fmt.Sprintf("LoggedIO: Byte budget of %v exceeded (%v bytes transferred)\n",
+			budget, after))
+	}
+}
@@ -0,0 +1,36 @@
+package loggedio
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestTotalByteBudget(t *testing.T) {
+	proxied := &MockIO{}
+	buffer := &bytes.Buffer{}
+	logged := StringToWriter(proxied, buffer, "R [%v]\n", "W [%v]\n", "E [%v: %v]\n", "C\n")
+	logged.TotalByteBudget = 6
+
+	_, err := logged.Write([]byte("abcd"))
+	expectNoError(t, err)
+	n, err := logged.Read(make([]byte, 3))
+	expectNoError(t, err)
+	expectNumber(t, 3, n)
+
+	n, err = logged.Write([]byte("more"))
+	if err != ErrBudgetExceeded {
+		t.Fatalf("Expected ErrBudgetExceeded but got %v", err)
+	}
+	expectNumber(t, 0, n)
+	_, err = logged.Read(make([]byte, 3))
+	if err != ErrBudgetExceeded {
+		t.Fatalf("Expected ErrBudgetExceeded but got %v", err)
+	}
+	expectString(t, "abcd", string(proxied.WriteContents))
+	expectBufferContents(t, buffer, ""+
+		"W [abcd]\n"+
+		"LoggedIO: Byte budget of 6 exceeded (7 bytes transferred)\n"+
+		"R [abc]\n"+
+		"E [Write(): LoggedIO: total byte budget exceeded]\n"+
+		"E [Read(): LoggedIO: total byte budget exceeded]\n")
+}
@@ -0,0 +1,131 @@
+package loggedio
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"log"
+	"os"
+	"sync"
+)
+
+// The layout of a circular file: a header of circularFileMagic followed by the
+// big-endian uint64 capacity, head (the offset into the data where the next
+// byte goes), and total number of bytes ever written, then the data itself.
+var circularFileMagic = []byte("LIOCIRC1")
+
+const circularFileHeaderSize = 32
+
+// DumpToCircularFile creates a logged I/O proxy that dumps the raw bytes of all
+// reads and writes (in the order they occurred) to a circular file that never
+// grows beyond maxBytes of data: once it's full, new data overwrites the
+// oldest. Use ReadCircularFile() to read the data back in order. Errors and
+// close events are not dumped. The file is closed by Close() or
+// CloseLogging().
+//
+// If the file can't be created, the error is logged via the go log and
+// nothing is dumped.
+func DumpToCircularFile(proxiedObject interface{}, filename string, maxBytes int64) *LoggedIOProxy {
+	var writer io.Writer = ioutil.Discard
+	if file, err := createCircularFile(filename, maxBytes); err != nil {
+		log.Printf("LoggedIO: Error creating %v: %v", filename, err)
+	} else {
+		writer = file
+	}
+	this := DumpToWriters(proxiedObject, writer, writer, ioutil.Discard, "", "")
+	this.ownWriters(writer)
+	return this
+}
+
+// ReadCircularFile returns the data held in a circular file written by
+// DumpToCircularFile, oldest first.
+func ReadCircularFile(filename string) ([]byte, error) {
+	contents, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	if len(contents) < circularFileHeaderSize || !bytes.HasPrefix(contents, circularFileMagic) {
+		return nil, fmt.Errorf("LoggedIO: %v is not a circular file", filename)
+	}
+	capacity := binary.BigEndian.Uint64(contents[8:])
+	head := binary.BigEndian.Uint64(contents[16:])
+	total := binary.BigEndian.Uint64(contents[24:])
+	data := contents[circularFileHeaderSize:]
+	if total <= capacity {
+		if uint64(len(data)) < total {
+			return nil, fmt.Errorf("LoggedIO: circular file %v is truncated", filename)
+		}
+		return data[:total], nil
+	}
+	if uint64(len(data)) < capacity || head >= capacity {
+		return nil, fmt.Errorf("LoggedIO: circular file %v is truncated", filename)
+	}
+	return append(append([]byte(nil), data[head:capacity]...), data[:head]...), nil
+}
+
+type circularFile struct {
+	mutex    sync.Mutex
+	file     *os.File
+	capacity int64
+	head     int64
+	total    uint64
+}
+
+func createCircularFile(filename string, capacity int64) (*circularFile, error) {
+	if capacity <= 0 {
+		return nil, fmt.Errorf("capacity must be greater than 0")
+	}
+	file, err := os.Create(filename)
+	if err != nil {
+		return nil, err
+	}
+	this := &circularFile{file: file, capacity: capacity}
+	if err := this.writeHeader(); err != nil {
+		file.Close()
+		return nil, err
+	}
+	return this, nil
+}
+
+func (this *circularFile) Write(b []byte) (n int, err error) {
+	this.mutex.Lock()
+	defer this.mutex.Unlock()
+	n = len(b)
+	this.total += uint64(len(b))
+	if int64(len(b)) >= this.capacity {
+		// Only the end of b fits, and it fills the whole file.
+		b = b[int64(len(b))-this.capacity:]
+		this.head = 0
+	}
+	for len(b) > 0 {
+		chunk := b
+		if room := this.capacity - this.head; int64(len(chunk)) > room {
+			chunk = chunk[:room]
+		}
+		if _, err = this.file.WriteAt(chunk, circularFileHeaderSize+this.head); err != nil {
+			return 0, err
+		}
+		this.head = (this.head + int64(len(chunk))) % this.capacity
+		b = b[len(chunk):]
+	}
+	if err = this.writeHeader(); err != nil {
+		return 0, err
+	}
+	return
+}
+
+func (this *circularFile) writeHeader() error {
+	header := make([]byte, circularFileHeaderSize)
+	copy(header, circularFileMagic)
+	binary.BigEndian.PutUint64(header[8:], uint64(this.capacity))
+	binary.BigEndian.PutUint64(header[16:], uint64(this.head))
+	binary.BigEndian.PutUint64(header[24:], this.total)
+	_, err := this.file.WriteAt(header, 0)
+	return err
+}
+
+func (this *circularFile) Close() error {
+	return this.file.Close()
+}
@@ -0,0 +1,45 @@
+package loggedio
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+)
+
+func TestDumpToCircularFile(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "dump.ring")
+	logged := DumpToCircularFile(&MockIO{}, filename, 10)
+
+	logged.Write([]byte("0123"))
+	contents, err := ReadCircularFile(filename)
+	expectNoError(t, err)
+	expectString(t, "0123", string(contents))
+
+	logged.Write([]byte("456789"))
+	contents, err = ReadCircularFile(filename)
+	expectNoError(t, err)
+	expectString(t, "0123456789", string(contents))
+
+	logged.Write([]byte("ABCD"))
+	logged.Read(make([]byte, 3))
+	contents, err = ReadCircularFile(filename)
+	expectNoError(t, err)
+	expectString(t, "789ABCDabc", string(contents))
+
+	logged.Write([]byte("the quick brown fox"))
+	contents, err = ReadCircularFile(filename)
+	expectNoError(t, err)
+	expectString(t, " brown fox", string(contents))
+
+	expectNoError(t, logged.CloseLogging())
+	raw, err := ioutil.ReadFile(filename)
+	expectNoError(t, err)
+	expectNumber(t, circularFileHeaderSize+10, len(raw))
+}
+
+func TestReadCircularFileInvalid(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "not.ring")
+	expectNoError(t, ioutil.WriteFile(filename, []byte("hello"), 0644))
+	_, err := ReadCircularFile(filename)
+	expectError(t, err)
+}
@@ -0,0 +1,82 @@
+package loggedio
+
+import (
+	"errors"
+	"io"
+	"net"
+	"os"
+	"syscall"
+)
+
+// ErrorClass is a broad category of error, allowing error reports to be
+// filtered or highlighted by severity.
+type ErrorClass int
+
+const (
+	// Any error not covered by the other classes.
+	ErrorClassOther ErrorClass = iota
+	// A transient condition that may clear up if retried: an interrupted call,
+	// a resource that's temporarily unavailable, or running out of file
+	// descriptors (see temporaryErrors).
+	ErrorClassTemporary
+	// A net.Error reporting itself as a timeout, or os.ErrDeadlineExceeded.
+	ErrorClassTimeout
+	// io.EOF, or an error wrapping it.
+	ErrorClassEOF
+	// net.ErrClosed, or an error wrapping it.
+	ErrorClassClosed
+)
+
+var errorClassNames = []string{
+	ErrorClassOther:     "other",
+	ErrorClassTemporary: "temporary",
+	ErrorClassTimeout:   "timeout",
+	ErrorClassEOF:       "eof",
+	ErrorClassClosed:    "closed",
+}
+
+func (this ErrorClass) String() string {
+	if this >= 0 && int(this) < len(errorClassNames) {
+		return errorClassNames[this]
+	}
+	return "unknown"
+}
+
+// The errors classified as ErrorClassTemporary. net.Error.Temporary() isn't
+// used, since it's deprecated and most errors it reports are not temporary.
+var temporaryErrors = []error{
+	syscall.EINTR,
+	syscall.EAGAIN,
+	syscall.ECONNABORTED,
+	syscall.EMFILE,
+	syscall.ENFILE,
+}
+
+// ClassifyError returns the class of err. Timeouts take precedence over
+// temporary errors, since some timeouts (such as EAGAIN from a non-blocking
+// call with a deadline) are also temporary.
+func ClassifyError(err error) ErrorClass {
+	if errors.Is(err, io.EOF) {
+		return ErrorClassEOF
+	}
+	if errors.Is(err, net.ErrClosed) {
+		return ErrorClassClosed
+	}
+	var netErr net.Error
+	if (errors.As(err, &netErr) && netErr.Timeout()) || errors.Is(err, os.ErrDeadlineExceeded) {
+		return ErrorClassTimeout
+	}
+	for _, temporary := range temporaryErrors {
+		if errors.Is(err, temporary) {
+			return ErrorClassTemporary
+		}
+	}
+	return ErrorClassOther
+}
+
+// SetClassifiedErrorCallback sets a function that receives errors along with
+// their class, overriding the proxy's normal error reporting (in the same way
+// as SetNotifyCallback does for notifications).
+func (this *LoggedIOProxy) SetClassifiedErrorCallback(reportError func(location string, err error, class ErrorClass)) {
+	this.reportClassifiedError = reportError
+}
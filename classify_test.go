@@ -0,0 +1,54 @@
+package loggedio
+
+import (
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"syscall"
+	"testing"
+)
+
+type MockTemporaryError struct{}
+
+func (this MockTemporaryError) Error() string   { return "try again" }
+func (this MockTemporaryError) Timeout() bool   { return false }
+func (this MockTemporaryError) Temporary() bool { return true }
+
+func TestClassifyError(t *testing.T) {
+	assertClass := func(expected ErrorClass, err error) {
+		if actual := ClassifyError(err); actual != expected {
+			t.Errorf("Expected %v to be classified %v but got %v", err, expected, actual)
+		}
+	}
+	assertClass(ErrorClassTemporary, &net.OpError{Op: "accept", Err: os.NewSyscallError("accept", syscall.EMFILE)})
+	assertClass(ErrorClassTemporary, fmt.Errorf("wrapped: %w", syscall.EINTR))
+	// Temporary() alone is deprecated, and no longer trusted.
+	assertClass(ErrorClassOther, MockTemporaryError{})
+	assertClass(ErrorClassOther, &net.OpError{Op: "read", Err: os.NewSyscallError("read", syscall.ECONNRESET)})
+	assertClass(ErrorClassTimeout, MockTimeoutError{})
+	assertClass(ErrorClassTimeout, fmt.Errorf("wrapped: %w", os.ErrDeadlineExceeded))
+	assertClass(ErrorClassEOF, io.EOF)
+	assertClass(ErrorClassEOF, fmt.Errorf("wrapped: %w", io.EOF))
+	assertClass(ErrorClassClosed, net.ErrClosed)
+	assertClass(ErrorClassClosed, &net.OpError{Op: "read", Err: net.ErrClosed})
+	assertClass(ErrorClassOther, generateError())
+}
+
+func TestClassifiedErrorCallback(t *testing.T) {
+	var classes []ErrorClass
+	var locations []string
+	logged := Generic(&MockTimeoutReader{},
+		func([]byte) {}, func([]byte) {},
+		func(string, error) { t.Errorf("Expected the classified callback to be used") },
+		func() {})
+	logged.SetClassifiedErrorCallback(func(location string, err error, class ErrorClass) {
+		locations = append(locations, location)
+		classes = append(classes, class)
+	})
+
+	logged.Read(make([]byte, 1))
+	expectNumber(t, 1, len(classes))
+	expectString(t, "Read() timeout", locations[0])
+	expectString(t, "timeout", classes[0].String())
+}
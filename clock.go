@@ -0,0 +1,73 @@
+package loggedio
+
+import (
+	"time"
+)
+
+// Clock is the source of time for all time-based reporting. The system clock
+// is used by default, but it can be replaced via SetClock() (usually for
+// testing).
+type Clock interface {
+	Now() time.Time
+}
+
+type systemClock struct{}
+
+func (this systemClock) Now() time.Time {
+	return time.Now()
+}
+
+// Ticker delivers ticks on a channel at a fixed interval, like time.Ticker.
+type Ticker interface {
+	C() <-chan time.Time
+	Stop()
+}
+
+// TickerClock is a Clock that can also create tickers. Time-based features
+// that run in the background (such as IdleMarkerInterval) use the clock's
+// tickers if it implements this interface, or real time tickers otherwise.
+type TickerClock interface {
+	Clock
+	NewTicker(interval time.Duration) Ticker
+}
+
+type systemTicker struct {
+	ticker *time.Ticker
+}
+
+func (this systemTicker) C() <-chan time.Time {
+	return this.ticker.C
+}
+
+func (this systemTicker) Stop() {
+	this.ticker.Stop()
+}
+
+func (this *LoggedIOProxy) newTicker(interval time.Duration) Ticker {
+	if clock, ok := this.clock.(TickerClock); ok {
+		return clock.NewTicker(interval)
+	}
+	return systemTicker{time.NewTicker(interval)}
+}
+
+// Timer is a call scheduled to run once after a delay, like time.Timer.
+type Timer interface {
+	// Stop prevents the call from running, returning false if it already ran
+	// or was already stopped.
+	Stop() bool
+}
+
+// TimerClock is a Clock that can also schedule calls. Features that act after
+// a delay (such as CoalesceWrites) use the clock's timers if it implements
+// this interface, or real time timers otherwise.
+type TimerClock interface {
+	Clock
+	AfterFunc(delay time.Duration, function func()) Timer
+}
+
+func (this *LoggedIOProxy) afterFunc(delay time.Duration, function func()) Timer {
+	if clock, ok := this.clock.(TimerClock); ok {
+		return clock.AfterFunc(delay, function)
+	}
+	return time.AfterFunc(delay, function)
+}
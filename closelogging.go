@@ -0,0 +1,63 @@
+package loggedio
+
+import (
+	"io"
+	"os"
+	"sync/atomic"
+)
+
+// CloseLogging stops all reporting by this proxy without closing the proxied
+// object, which remains fully usable via Read(), Write(), etc. Any buffered or
+// coalesced report output is flushed first, and report targets that the proxy
+// created itself (such as the files opened by DumpToFiles) are closed.
+// Writers that were passed in by the caller are left open.
+//
+// Reporting can't be resumed afterwards (SetEnabled() has no effect). Calling
+// CloseLogging more than once does nothing. The first error encountered while
+// flushing or closing is returned.
+func (this *LoggedIOProxy) CloseLogging() (err error) {
+	if !atomic.CompareAndSwapInt32(&this.loggingClosed, 0, 1) {
+		return nil
+	}
+	if this.coalescer.window > 0 {
+		this.flushCoalescedWrites()
+	}
+	this.stopBackgroundTasks()
+	atomic.StoreInt32(&this.disabled, 1)
+	if this.target != nil {
+		err = this.target.close()
+	}
+	if closeErr := this.closeOwnedWriters(); err == nil {
+		err = closeErr
+	}
+	return
+}
+
+// closeOwnedWriters closes the report writers recorded by ownWriters(), the
+// first time it's called. Later calls do nothing.
+func (this *LoggedIOProxy) closeOwnedWriters() (err error) {
+	if !atomic.CompareAndSwapInt32(&this.ownedClosed, 0, 1) {
+		return nil
+	}
+	for _, closer := range this.ownedClosers {
+		if closeErr := closer.Close(); err == nil {
+			err = closeErr
+		}
+	}
+	return
+}
+
+// ownWriters records which of writers were created by the proxy (as opposed to
+// being passed in), so that Close() and CloseLogging() can close them.
+func (this *LoggedIOProxy) ownWriters(writers ...io.Writer) {
+	for _, writer := range writers {
+		switch writer := writer.(type) {
+		case *os.File:
+			if writer != os.Stdout && writer != os.Stderr {
+				this.ownedClosers = append(this.ownedClosers, writer)
+			}
+		case *lazyFileWriter, *circularFile:
+			this.ownedClosers = append(this.ownedClosers, writer.(io.Closer))
+		}
+	}
+}
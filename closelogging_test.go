@@ -0,0 +1,95 @@
+package loggedio
+
+import (
+	"bytes"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestCloseLogging(t *testing.T) {
+	dir := t.TempDir()
+	readFile := filepath.Join(dir, "read.bin")
+	proxied := &MockIO{}
+	logged := DumpToFiles(proxied, readFile, "null", "stderr", "E [%v: %v]", "C")
+
+	logged.Read(make([]byte, 3))
+	expectNoError(t, logged.CloseLogging())
+	n, err := logged.Read(make([]byte, 2))
+	expectNoError(t, err)
+	expectNumber(t, 2, n)
+	n, err = logged.Write([]byte("test"))
+	expectNoError(t, err)
+	expectNumber(t, 4, n)
+	expectString(t, "test", string(proxied.WriteContents))
+	expectNumber(t, 0, proxied.CloseCallCount)
+
+	contents, err := ioutil.ReadFile(readFile)
+	expectNoError(t, err)
+	expectString(t, "abc", string(contents))
+
+	expectNumber(t, 1, len(logged.ownedClosers))
+	_, err = logged.ownedClosers[0].(*os.File).Write([]byte("x"))
+	if !errors.Is(err, os.ErrClosed) {
+		t.Errorf("Expected the dump file to be closed, but writing to it returned %v", err)
+	}
+
+	logged.SetEnabled(true)
+	if logged.IsEnabled() {
+		t.Errorf("Expected logging to stay off after CloseLogging()")
+	}
+}
+
+func TestCloseLoggingFlushesBuffer(t *testing.T) {
+	buffer := &bytes.Buffer{}
+	logged := StringToWriter(&MockIO{}, buffer, "R [%v]", "W [%v]", "E [%v: %v]", "C")
+	expectNoError(t, logged.BufferOutput(1024, 0))
+
+	logged.Write([]byte("before"))
+	expectBufferContents(t, buffer, "")
+	expectNoError(t, logged.CloseLogging())
+	expectBufferContents(t, buffer, "W [before]")
+
+	logged.Write([]byte("after"))
+	logged.Close()
+	expectBufferContents(t, buffer, "W [before]")
+}
+
+func expectOwnedWritersClosed(t *testing.T, logged *LoggedIOProxy) {
+	expectNumber(t, 1, len(logged.ownedClosers))
+	for _, closer := range logged.ownedClosers {
+		file, ok := closer.(*os.File)
+		if circular, isCircular := closer.(*circularFile); isCircular {
+			file, ok = circular.file, true
+		}
+		if !ok {
+			t.Errorf("Unexpected owned writer %T", closer)
+			continue
+		}
+		if _, err := file.Write([]byte("x")); !errors.Is(err, os.ErrClosed) {
+			t.Errorf("Expected %v to be closed, but writing to it returned %v", file.Name(), err)
+		}
+	}
+}
+
+func TestCloseClosesOwnedWriters(t *testing.T) {
+	dir := t.TempDir()
+	for _, logged := range []*LoggedIOProxy{
+		DumpToFileAuto(&MockIO{}, filepath.Join(dir, "dump.txt")),
+		DumpToCircularFile(&MockIO{}, filepath.Join(dir, "dump.ring"), 10),
+	} {
+		logged.Write([]byte("test"))
+		expectNoError(t, logged.Close())
+		expectOwnedWritersClosed(t, logged)
+
+		// Closing again, either way, doesn't close the files again.
+		expectNoError(t, logged.Close())
+		expectNoError(t, logged.CloseLogging())
+	}
+
+	contents, err := ioutil.ReadFile(filepath.Join(dir, "dump.txt"))
+	expectNoError(t, err)
+	expectString(t, "W [test]\nC\n", string(contents))
+}
@@ -0,0 +1,63 @@
+package loggedio
+
+import (
+	"sync"
+	"time"
+)
+
+// CoalesceWrites makes the proxy report consecutive writes made within window
+// of the first one as a single combined write event. The combined event is
+// reported when a write falls outside the window, when any other event (such
+// as a read) is reported, or when window has passed with no further events.
+// Only reporting is affected: writes still pass through to the proxied object
+// immediately. The window is measured using the proxy's clock, and the flush
+// after it passes is scheduled with it (see TimerClock).
+//
+// This must be called before the proxy is first used. A window of 0 disables
+// coalescing.
+func (this *LoggedIOProxy) CoalesceWrites(window time.Duration) {
+	this.coalescer.window = window
+}
+
+type writeCoalescer struct {
+	mutex      sync.Mutex
+	window     time.Duration
+	pending    []byte
+	startedAt  time.Time
+	completion uint64
+	timer      Timer
+}
+
+func (this *LoggedIOProxy) coalesceWrite(b []byte, completion uint64) {
+	coalescer := &this.coalescer
+	now := this.clock.Now()
+	coalescer.mutex.Lock()
+	if coalescer.pending != nil && now.Sub(coalescer.startedAt) > coalescer.window {
+		coalescer.mutex.Unlock()
+		this.flushCoalescedWrites()
+		coalescer.mutex.Lock()
+	}
+	if coalescer.pending == nil {
+		coalescer.startedAt = now
+		coalescer.completion = completion
+		coalescer.timer = this.afterFunc(coalescer.window, this.flushCoalescedWrites)
+	}
+	coalescer.pending = append(coalescer.pending, b...)
+	coalescer.mutex.Unlock()
+}
+
+func (this *LoggedIOProxy) flushCoalescedWrites() {
+	coalescer := &this.coalescer
+	coalescer.mutex.Lock()
+	pending := coalescer.pending
+	completion := coalescer.completion
+	coalescer.pending = nil
+	if coalescer.timer != nil {
+		coalescer.timer.Stop()
+		coalescer.timer = nil
+	}
+	coalescer.mutex.Unlock()
+	if pending != nil {
+		this.report(&Event{Type: EventWrite, Data: pending, Completion: completion})
+	}
+}
@@ -0,0 +1,56 @@
+package loggedio
+
+import (
+	"bytes"
+	"testing"
+	"time"
+)
+
+func TestCoalesceWrites(t *testing.T) {
+	buffer := &bytes.Buffer{}
+	proxied := &MockIO{}
+	logged := StringToWriter(proxied, buffer, "R [%v]", "W [%v]", "E [%v: %v]", "C")
+	clock := newMockClock()
+	logged.SetClock(clock)
+	logged.CoalesceWrites(time.Hour)
+
+	logged.Write([]byte("a"))
+	clock.Advance(time.Minute)
+	logged.Write([]byte("b"))
+	clock.Advance(time.Minute)
+	logged.Write([]byte("c"))
+	expectString(t, "abc", string(proxied.WriteContents))
+	expectBufferContents(t, buffer, "")
+
+	logged.Read(make([]byte, 1))
+	expectBufferContents(t, buffer, "W [abc]R [a]")
+
+	buffer.Reset()
+	logged.Write([]byte("d"))
+	clock.Advance(2 * time.Hour)
+	logged.Write([]byte("e"))
+	expectBufferContents(t, buffer, "W [d]")
+	logged.Close()
+	expectBufferContents(t, buffer, "W [d]W [e]C")
+}
+
+func TestCoalesceWritesTimerFlush(t *testing.T) {
+	buffer := &bytes.Buffer{}
+	logged := StringToWriter(&MockIO{}, buffer, "R [%v]", "W [%v]", "E [%v: %v]", "C")
+	clock := newMockClock()
+	logged.SetClock(clock)
+	logged.CoalesceWrites(100 * time.Millisecond)
+
+	logged.Write([]byte("a"))
+	clock.Advance(60 * time.Millisecond)
+	logged.Write([]byte("b"))
+	clock.Advance(39 * time.Millisecond)
+	expectBufferContents(t, buffer, "")
+	clock.Advance(time.Millisecond)
+	expectBufferContents(t, buffer, "W [ab]")
+
+	logged.Write([]byte("c"))
+	logged.Read(make([]byte, 1))
+	clock.Advance(time.Hour)
+	expectBufferContents(t, buffer, "W [ab]W [c]R [a]")
+}
@@ -0,0 +1,97 @@
+package loggedio
+
+import (
+	"io"
+	"os"
+	"strings"
+)
+
+// ColorMode determines whether formatted output is colored with ANSI escape
+// codes.
+type ColorMode int
+
+const (
+	// Never color output.
+	ColorNever ColorMode = iota
+	// Color output only if the proxy writes to a terminal. Proxies that
+	// report via a logger or printf function are never colored in this mode,
+	// since their destination can't be determined.
+	ColorAuto
+	// Always color output.
+	ColorAlways
+)
+
+// ColorScheme holds the ANSI escape sequences used to color each event type.
+// An empty sequence leaves that event type uncolored.
+type ColorScheme struct {
+	Read   string
+	Write  string
+	Error  string
+	Close  string
+	Notify string
+}
+
+// DefaultColorScheme colors reads green, writes blue, errors red, and
+// notifications yellow.
+var DefaultColorScheme = ColorScheme{
+	Read:   "\x1b[32m",
+	Write:  "\x1b[34m",
+	Error:  "\x1b[31m",
+	Notify: "\x1b[33m",
+}
+
+const colorReset = "\x1b[0m"
+
+func (this ColorScheme) forEvent(eventType EventType) string {
+	switch eventType {
+	case EventRead:
+		return this.Read
+	case EventWrite:
+		return this.Write
+	case EventError:
+		return this.Error
+	case EventClose:
+		return this.Close
+	case EventNotify:
+		return this.Notify
+	}
+	return ""
+}
+
+// isTerminal returns true if writer is a terminal. It's a variable so that
+// tests can replace it.
+var isTerminal = func(writer io.Writer) bool {
+	file, ok := writer.(*os.File)
+	if !ok {
+		return false
+	}
+	info, err := file.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
+
+// colorFor returns the escape sequence to color an event of eventType with, or
+// an empty string if it shouldn't be colored.
+func (this *textFormatter) colorFor(eventType EventType) string {
+	switch this.proxy.Color {
+	case ColorAlways:
+	case ColorAuto:
+		if this.writer == nil || !this.proxy.target.writesToTerminal() {
+			return ""
+		}
+	default:
+		return ""
+	}
+	return this.proxy.Colors.forEvent(eventType)
+}
+
+// withColorEnd appends a color reset to text (before its trailing newline, if
+// any) if color is set.
+func withColorEnd(color string, text string) string {
+	if color == "" {
+		return text
+	}
+	if strings.HasSuffix(text, "\n") {
+		return text[:len(text)-1] + colorReset + "\n"
+	}
+	return text + colorReset
+}
@@ -0,0 +1,91 @@
+package loggedio
+
+import (
+	"bytes"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestColorAlways(t *testing.T) {
+	buffer := &bytes.Buffer{}
+	logged := StringToWriter(&MockIO{FailAfterReadByteCount: 2}, buffer, "R [%v]\n", "W [%v]\n", "E [%v: %v]\n", "C\n")
+	logged.Color = ColorAlways
+
+	logged.Write([]byte("hi"))
+	logged.Read(make([]byte, 3))
+	logged.Close()
+	expectBufferContents(t, buffer, ""+
+		"\x1b[34mW [hi]\x1b[0m\n"+
+		"\x1b[32mR [ab]\x1b[0m\n"+
+		"\x1b[31mE [Read(): ERROR!]\x1b[0m\n"+
+		"C\n")
+}
+
+func TestColorCustomScheme(t *testing.T) {
+	buffer := &bytes.Buffer{}
+	logged := StringToWriter(&MockIO{}, buffer, "R [%v]", "W [%v]", "E [%v: %v]", "C")
+	logged.Color = ColorAlways
+	logged.Colors = ColorScheme{Write: "<w>"}
+
+	logged.Write([]byte("hi"))
+	logged.Read(make([]byte, 1))
+	expectBufferContents(t, buffer, "<w>W [hi]\x1b[0mR [a]")
+}
+
+func TestColorStreamedHex(t *testing.T) {
+	buffer := &bytes.Buffer{}
+	logged := HexToWriter(&MockIO{}, buffer, "R [%v]\n", "W [%v]\n", "E [%v: %v]\n", "C\n")
+	logged.Color = ColorAlways
+	payload := generateBytes(hexStreamingThreshold + 1)
+	logged.Write(payload)
+	expectBufferContents(t, buffer, "\x1b[34mW ["+toHex(payload)+"]\x1b[0m\n")
+}
+
+func TestColorAutoNotTerminal(t *testing.T) {
+	buffer := &bytes.Buffer{}
+	logged := StringToWriter(&MockIO{}, buffer, "R [%v]", "W [%v]", "E [%v: %v]", "C")
+	logged.Color = ColorAuto
+	logged.Write([]byte("hi"))
+	expectBufferContents(t, buffer, "W [hi]")
+
+	file, err := os.Create(filepath.Join(t.TempDir(), "log.txt"))
+	expectNoError(t, err)
+	defer file.Close()
+	logged = StringToWriter(&MockIO{}, file, "R [%v]", "W [%v]", "E [%v: %v]", "C")
+	logged.Color = ColorAuto
+	logged.Write([]byte("hi"))
+	contents, err := ioutil.ReadFile(file.Name())
+	expectNoError(t, err)
+	if strings.Contains(string(contents), "\x1b") {
+		t.Errorf("Expected no color codes in %q", contents)
+	}
+	expectString(t, "W [hi]", string(contents))
+}
+
+func TestColorAutoTerminal(t *testing.T) {
+	terminal := &bytes.Buffer{}
+	originalIsTerminal := isTerminal
+	isTerminal = func(writer io.Writer) bool { return writer == terminal }
+	defer func() { isTerminal = originalIsTerminal }()
+
+	logged := StringToWriter(&MockIO{}, terminal, "R [%v]", "W [%v]", "E [%v: %v]", "C")
+	logged.Color = ColorAuto
+	expectNoError(t, logged.SetReportTimeout(time.Second))
+	logged.Write([]byte("hi"))
+	expectBufferContents(t, terminal, "\x1b[34mW [hi]\x1b[0m")
+
+	buffer := &bytes.Buffer{}
+	expectNoError(t, logged.SetTarget(buffer))
+	logged.Write([]byte("hi"))
+	expectBufferContents(t, buffer, "W [hi]")
+
+	expectNoError(t, logged.SetTarget(terminal))
+	terminal.Reset()
+	logged.Write([]byte("hi"))
+	expectBufferContents(t, terminal, "\x1b[34mW [hi]\x1b[0m")
+}
@@ -0,0 +1,88 @@
+package loggedio
+
+import (
+	"io"
+	"sync"
+)
+
+// CombineWrites makes the proxy collect the data passed to Write() in a buffer
+// instead of writing it to the proxied object right away. The buffer is
+// written to the proxied object in a single call (and reported as a single
+// write event) once it holds at least threshold bytes, and when Flush() or
+// Close() is called. A threshold of 0 only writes the buffer on Flush() and
+// Close(), so callers must call one of them for the data to be sent.
+//
+// Write() always succeeds while data is being collected. An error from
+// writing the buffer to the proxied object is returned by the call that
+// triggered it (Write(), Flush(), or Close()). If that call was a Write(), it
+// returns how many of its own bytes were written, and its unwritten bytes are
+// discarded so that the caller can retry them without sending duplicates.
+// Data from earlier calls to Write() that is still unwritten stays in the
+// buffer.
+//
+// This must be called before the proxy is first used.
+func (this *LoggedIOProxy) CombineWrites(threshold int) {
+	this.combiner.isEnabled = true
+	this.combiner.threshold = threshold
+}
+
+type writeCombiner struct {
+	mutex     sync.Mutex
+	isEnabled bool
+	threshold int
+	pending   []byte
+}
+
+func (this *LoggedIOProxy) combineWrite(writer io.Writer, b []byte) (n int, err error) {
+	combiner := &this.combiner
+	combiner.mutex.Lock()
+	defer combiner.mutex.Unlock()
+	earlier := len(combiner.pending)
+	combiner.pending = append(combiner.pending, b...)
+	if combiner.threshold == 0 || len(combiner.pending) < combiner.threshold {
+		return len(b), nil
+	}
+
+	written, err := this.writeCombined(writer)
+	if err == nil {
+		return len(b), nil
+	}
+	if written > earlier {
+		n = written - earlier
+	}
+	// Only earlier, already acknowledged data stays pending.
+	if remaining := earlier - written; remaining > 0 {
+		combiner.pending = combiner.pending[:remaining]
+	} else {
+		combiner.pending = combiner.pending[:0]
+	}
+	return n, err
+}
+
+func (this *LoggedIOProxy) flushCombinedWrites() error {
+	combiner := &this.combiner
+	combiner.mutex.Lock()
+	defer combiner.mutex.Unlock()
+	if len(combiner.pending) == 0 {
+		return nil
+	}
+	writer, ok := this.proxiedObject.(io.Writer)
+	if err := this.checkImplements(ok, "Write()", "io.Writer"); err != nil {
+		return err
+	}
+	_, err := this.writeCombined(writer)
+	return err
+}
+
+// writeCombined writes the pending data, removing the part that was written
+// from the buffer, and returns how much was written. It must be called with
+// the combiner's mutex held.
+func (this *LoggedIOProxy) writeCombined(writer io.Writer) (n int, err error) {
+	combiner := &this.combiner
+	n, err = this.write(writer, combiner.pending)
+	if err == nil && n < len(combiner.pending) {
+		err = io.ErrShortWrite
+	}
+	combiner.pending = combiner.pending[:copy(combiner.pending, combiner.pending[n:])]
+	return
+}
@@ -0,0 +1,99 @@
+package loggedio
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestCombineWrites(t *testing.T) {
+	buffer := &bytes.Buffer{}
+	proxied := &MockIO{}
+	logged := StringToWriter(proxied, buffer, "R [%v]\n", "W [%v]\n", "E [%v: %v]\n", "C\n")
+	logged.CombineWrites(0)
+
+	for _, part := range []string{"a", "bc", "def"} {
+		n, err := logged.Write([]byte(part))
+		expectNoError(t, err)
+		expectNumber(t, len(part), n)
+	}
+	expectString(t, "", string(proxied.WriteContents))
+	expectBufferContents(t, buffer, "")
+
+	expectNoError(t, logged.Flush())
+	expectString(t, "abcdef", string(proxied.WriteContents))
+	expectNumber(t, 1, int(logged.Stats().WriteCalls))
+	expectBufferContents(t, buffer, "W [abcdef]\n")
+
+	buffer.Reset()
+	logged.Write([]byte("gh"))
+	expectNoError(t, logged.Close())
+	expectString(t, "abcdefgh", string(proxied.WriteContents))
+	expectBufferContents(t, buffer, "W [gh]\nC\n")
+}
+
+func TestCombineWritesThreshold(t *testing.T) {
+	buffer := &bytes.Buffer{}
+	proxied := &MockIO{}
+	logged := StringToWriter(proxied, buffer, "R [%v]\n", "W [%v]\n", "E [%v: %v]\n", "C\n")
+	logged.CombineWrites(4)
+
+	logged.Write([]byte("ab"))
+	logged.Write([]byte("c"))
+	expectBufferContents(t, buffer, "")
+	logged.Write([]byte("de"))
+	logged.Write([]byte("f"))
+	expectString(t, "abcde", string(proxied.WriteContents))
+	expectBufferContents(t, buffer, "W [abcde]\n")
+}
+
+func TestCombineWritesError(t *testing.T) {
+	buffer := &bytes.Buffer{}
+	proxied := &MockIO{FailAfterWriteByteCount: 2}
+	logged := StringToWriter(proxied, buffer, "R [%v]\n", "W [%v]\n", "E [%v: %v]\n", "C\n")
+	logged.CombineWrites(0)
+
+	_, err := logged.Write([]byte("abcd"))
+	expectNoError(t, err)
+	expectError(t, logged.Flush())
+	expectString(t, "ab", string(proxied.WriteContents))
+	expectBufferContents(t, buffer, "W [ab]\nE [Write(): ERROR!]\n")
+
+	buffer.Reset()
+	proxied.FailAfterWriteByteCount = 0
+	expectNoError(t, logged.Flush())
+	expectString(t, "abcd", string(proxied.WriteContents))
+	expectBufferContents(t, buffer, "W [cd]\n")
+}
+
+func TestCombineWritesThresholdError(t *testing.T) {
+	proxied := &MockIO{FailAfterWriteByteCount: 3}
+	logged := StringToWriter(proxied, &bytes.Buffer{}, "R [%v]\n", "W [%v]\n", "E [%v: %v]\n", "C\n")
+	logged.CombineWrites(4)
+
+	logged.Write([]byte("ab"))
+	n, err := logged.Write([]byte("cdef"))
+	expectError(t, err)
+	expectNumber(t, 1, n)
+	expectString(t, "abc", string(proxied.WriteContents))
+
+	proxied.FailAfterWriteByteCount = 1
+	logged.Write([]byte("gh"))
+	n, err = logged.Write([]byte("ij"))
+	expectError(t, err)
+	expectNumber(t, 0, n)
+
+	// Retrying the unwritten bytes must not send anything twice.
+	proxied.FailAfterWriteByteCount = 0
+	logged.Write([]byte("def"))
+	logged.Write([]byte("ij"))
+	expectNoError(t, logged.Flush())
+	expectString(t, "abcghdefij", string(proxied.WriteContents))
+}
+
+func TestCombineWritesFlushNonWriter(t *testing.T) {
+	logged := StringToWriter(&MockReader{}, &bytes.Buffer{}, "R [%v]\n", "W [%v]\n", "E [%v: %v]\n", "C\n")
+	logged.RecoverMethodMismatch = true
+	logged.CombineWrites(0)
+	logged.combiner.pending = []byte("abc")
+	expectError(t, logged.Flush())
+}
@@ -0,0 +1,33 @@
+package loggedio
+
+// Config is a snapshot of a proxy's reporting configuration, for display in
+// management or debug endpoints.
+type Config struct {
+	// The text formats, which are empty for proxies not built by the
+	// formatting proxy generators.
+	ReadFormat   string
+	WriteFormat  string
+	ErrorFormat  string
+	CloseMessage string
+
+	Encoding Encoding
+
+	// A description of where the proxy reports to, for example "log" or
+	// "writer *os.File". Empty if unknown.
+	Target string
+}
+
+// Config returns a snapshot of the proxy's current reporting configuration.
+func (this *LoggedIOProxy) Config() Config {
+	config := Config{Encoding: this.Encoding()}
+	if formatter := this.formatter; formatter != nil {
+		formatter.mutex.Lock()
+		config.ReadFormat = formatter.readFmt
+		config.WriteFormat = formatter.writeFmt
+		config.ErrorFormat = formatter.errorFmt
+		config.CloseMessage = formatter.closeMsg
+		config.Target = formatter.targetDescription
+		formatter.mutex.Unlock()
+	}
+	return config
+}
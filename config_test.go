@@ -0,0 +1,28 @@
+package loggedio
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestConfig(t *testing.T) {
+	logged := HexToWriter(&MockIO{}, &bytes.Buffer{}, "R [%v]", "W [%v]", "E [%v: %v]", "C")
+	config := logged.Config()
+	expectString(t, "Hex", config.Encoding.String())
+	expectString(t, "R [%v]", config.ReadFormat)
+	expectString(t, "W [%v]", config.WriteFormat)
+	expectString(t, "E [%v: %v]", config.ErrorFormat)
+	expectString(t, "C", config.CloseMessage)
+	expectString(t, "writer *bytes.Buffer", config.Target)
+
+	logged.SetReadFormat("DEBUG R [%v]")
+	expectString(t, "DEBUG R [%v]", logged.Config().ReadFormat)
+
+	logged = StringToLog(&MockIO{}, "R [%v]", "W [%v]", "E [%v: %v]", "C")
+	expectString(t, "log", logged.Config().Target)
+
+	logged, _ = NewWithMemorySink(&MockIO{})
+	config = logged.Config()
+	expectString(t, "", config.ReadFormat)
+	expectString(t, "", config.Target)
+}
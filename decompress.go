@@ -0,0 +1,48 @@
+package loggedio
+
+import (
+	"compress/flate"
+	"compress/gzip"
+	"fmt"
+	"io"
+)
+
+// Algorithm identifies a compression algorithm.
+type Algorithm int
+
+const (
+	// The gzip format (RFC 1952), as used by Content-Encoding: gzip.
+	AlgorithmGzip Algorithm = iota
+	// Raw DEFLATE data (RFC 1951) with no header.
+	AlgorithmFlate
+)
+
+// NewDecompressingReader wraps r in a decompressor for the specified algorithm,
+// and wraps the decompressor in a logged I/O proxy built by generate, so that
+// the DECOMPRESSED data is reported. The proxy's Stats() count decompressed
+// bytes. To also count or log the compressed bytes, pass in an r that is
+// itself a logged I/O proxy.
+//
+// Closing the returned reader closes the decompressor, but not r.
+//
+// Note: For AlgorithmGzip, this function reads the gzip header from r before
+// returning, and so blocks until the header arrives (which may take a while on
+// a live net.Conn). An error is returned if the header is invalid.
+func NewDecompressingReader(r io.Reader, algorithm Algorithm, generate ProxyGenerator) (io.ReadCloser, error) {
+	var decompressor io.ReadCloser
+	switch algorithm {
+	case AlgorithmGzip:
+		gzipReader, err := gzip.NewReader(r)
+		if err != nil {
+			return nil, err
+		}
+		decompressor = gzipReader
+	case AlgorithmFlate:
+		decompressor = flate.NewReader(r)
+	default:
+		return nil, fmt.Errorf("LoggedIO: Unknown compression algorithm %v", algorithm)
+	}
+	proxy := generate(decompressor)
+	proxy.SuppressEOF = true
+	return proxy, nil
+}
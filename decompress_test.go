@@ -0,0 +1,54 @@
+package loggedio
+
+import (
+	"bytes"
+	"compress/flate"
+	"compress/gzip"
+	"io/ioutil"
+	"testing"
+)
+
+func TestDecompressingReaderGzip(t *testing.T) {
+	compressed := &bytes.Buffer{}
+	compressor := gzip.NewWriter(compressed)
+	compressor.Write([]byte("hello world"))
+	compressor.Close()
+	compressedLength := compressed.Len()
+
+	buffer := &bytes.Buffer{}
+	rawCounter := DumpToWriters(compressed, &NullWriter{}, &NullWriter{}, &NullWriter{}, "", "")
+	reader, err := NewDecompressingReader(rawCounter, AlgorithmGzip, func(o interface{}) *LoggedIOProxy {
+		return StringToWriter(o, buffer, "R [%v]", "W [%v]", "E [%v: %v]", "C")
+	})
+	expectNoError(t, err)
+
+	contents, err := ioutil.ReadAll(reader)
+	expectNoError(t, err)
+	expectLength(t, contents, 11)
+	expectBufferContents(t, buffer, "R [hello world]")
+	expectNumber(t, 11, int(reader.(*LoggedIOProxy).Stats().BytesRead))
+	expectNumber(t, compressedLength, int(rawCounter.Stats().BytesRead))
+}
+
+func TestDecompressingReaderFlate(t *testing.T) {
+	compressed := &bytes.Buffer{}
+	compressor, _ := flate.NewWriter(compressed, flate.DefaultCompression)
+	compressor.Write([]byte("hello world"))
+	compressor.Close()
+
+	buffer := &bytes.Buffer{}
+	reader, err := NewDecompressingReader(compressed, AlgorithmFlate, func(o interface{}) *LoggedIOProxy {
+		return StringToWriter(o, buffer, "R [%v]", "W [%v]", "E [%v: %v]", "C")
+	})
+	expectNoError(t, err)
+
+	_, err = ioutil.ReadAll(reader)
+	expectNoError(t, err)
+	expectBufferContents(t, buffer, "R [hello world]")
+}
+
+func TestDecompressingReaderBadData(t *testing.T) {
+	_, err := NewDecompressingReader(bytes.NewBufferString("not gzip"), AlgorithmGzip,
+		func(o interface{}) *LoggedIOProxy { return StringToLog(o, "", "", "", "") })
+	expectError(t, err)
+}
@@ -0,0 +1,31 @@
+package loggedio
+
+// Direction identifies the direction of data flow through a proxy.
+type Direction int
+
+const (
+	// Data read from the proxied object.
+	DirectionRead Direction = iota
+	// Data written to the proxied object.
+	DirectionWrite
+)
+
+var directionNames = []string{
+	DirectionRead:  "read",
+	DirectionWrite: "write",
+}
+
+func (this Direction) String() string {
+	if this.isValid() {
+		return directionNames[this]
+	}
+	return "unknown"
+}
+
+func (this Direction) isValid() bool {
+	return this >= 0 && int(this) < len(directionNames)
+}
+
+func (this *LoggedIOProxy) passesFilter(direction Direction, b []byte) bool {
+	return this.Filter == nil || this.Filter(direction, b)
+}
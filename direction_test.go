@@ -0,0 +1,33 @@
+package loggedio
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestDirectionString(t *testing.T) {
+	expectString(t, "read", DirectionRead.String())
+	expectString(t, "write", DirectionWrite.String())
+	expectString(t, "unknown", Direction(5).String())
+}
+
+func TestFilter(t *testing.T) {
+	buffer := &bytes.Buffer{}
+	logged := StringToWriter(&MockIO{}, buffer, "R [%v]\n", "W [%v]\n", "E [%v: %v]\n", "C\n")
+	var directions []Direction
+	logged.Filter = func(direction Direction, data []byte) bool {
+		directions = append(directions, direction)
+		return string(data) != "secret"
+	}
+
+	logged.Read(make([]byte, 3))
+	logged.Write([]byte("secret"))
+	logged.Write([]byte("test"))
+
+	expectBufferContents(t, buffer, "R [abc]\nW [test]\n")
+	if len(directions) != 3 || directions[0] != DirectionRead ||
+		directions[1] != DirectionWrite || directions[2] != DirectionWrite {
+		t.Errorf("Expected filter directions [read write write] but got %v", directions)
+	}
+	expectNumber(t, 10, int(logged.Stats().BytesWritten))
+}
@@ -0,0 +1,53 @@
+package loggedio
+
+import (
+	"os"
+	"strconv"
+	"sync/atomic"
+)
+
+// EnabledEnvVar is the environment variable that sets whether new proxies
+// start out enabled. If it's set to a false value as understood by
+// strconv.ParseBool (such as "0" or "false"), new proxies start out disabled
+// until SetEnabled(true) is called. Any other value (or no value) leaves them
+// enabled.
+const EnabledEnvVar = "LOGGEDIO_ENABLED"
+
+func enabledFromEnvironment() bool {
+	value, ok := os.LookupEnv(EnabledEnvVar)
+	if !ok {
+		return true
+	}
+	enabled, err := strconv.ParseBool(value)
+	return enabled || err != nil
+}
+
+// SetEnabled turns all reporting by this proxy on or off. While disabled, no
+// events are formatted or delivered to any report target (including sinks and
+// mirrors), but I/O still passes through, and Stats() still counts it. It's
+// safe to call concurrently with I/O. It has no effect after CloseLogging().
+func (this *LoggedIOProxy) SetEnabled(enabled bool) {
+	if atomic.LoadInt32(&this.loggingClosed) != 0 {
+		return
+	}
+	var disabled int32
+	if !enabled {
+		disabled = 1
+	}
+	atomic.StoreInt32(&this.disabled, disabled)
+}
+
+// IsEnabled returns true if this proxy is currently reporting events.
+func (this *LoggedIOProxy) IsEnabled() bool {
+	return atomic.LoadInt32(&this.disabled) == 0 && atomic.LoadInt32(&this.loggingClosed) == 0
+}
+
+// ResumeAfterError resumes reporting after it was stopped by an error (see
+// StopLoggingAfterError). The next error stops it again.
+func (this *LoggedIOProxy) ResumeAfterError() {
+	atomic.StoreInt32(&this.silencedByError, 0)
+}
+
+func (this *LoggedIOProxy) isSilencedByError(eventType EventType) bool {
+	return eventType != EventClose && atomic.LoadInt32(&this.silencedByError) != 0
+}
@@ -0,0 +1,71 @@
+package loggedio
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestSetEnabled(t *testing.T) {
+	buffer := &bytes.Buffer{}
+	logged := StringToWriter(&MockIO{}, buffer, "R [%v]\n", "W [%v]\n", "E [%v: %v]\n", "C\n")
+	var sinkEvents int
+	logged.AddSink(SinkFunc(func(event *Event) { sinkEvents++ }))
+
+	logged.SetEnabled(false)
+	if logged.IsEnabled() {
+		t.Errorf("Expected proxy to be disabled")
+	}
+	logged.Read(make([]byte, 3))
+	logged.SetEnabled(true)
+	logged.Read(make([]byte, 2))
+
+	expectBufferContents(t, buffer, "R [ab]\n")
+	expectNumber(t, 1, sinkEvents)
+	expectNumber(t, 5, int(logged.Stats().BytesRead))
+}
+
+func TestEnabledEnvVar(t *testing.T) {
+	for _, test := range []struct {
+		value    string
+		expected bool
+	}{
+		{"0", false},
+		{"false", false},
+		{"1", true},
+		{"bogus", true},
+	} {
+		t.Setenv(EnabledEnvVar, test.value)
+		logged := StringToWriter(&MockIO{}, &bytes.Buffer{}, "R [%v]", "W [%v]", "E [%v: %v]", "C")
+		if logged.IsEnabled() != test.expected {
+			t.Errorf("%v=%q: Expected enabled=%v", EnabledEnvVar, test.value, test.expected)
+		}
+	}
+}
+
+func TestStopLoggingAfterError(t *testing.T) {
+	buffer := &bytes.Buffer{}
+	proxied := &MockIO{}
+	logged := StringToWriter(proxied, buffer, "R [%v]\n", "W [%v]\n", "E [%v: %v]\n", "C\n")
+	logged.StopLoggingAfterError = true
+
+	logged.Read(make([]byte, 1))
+	proxied.FailNextOperations = true
+	logged.Read(make([]byte, 1))
+	logged.Write([]byte("x"))
+	proxied.FailNextOperations = false
+	logged.Read(make([]byte, 1))
+	logged.Write([]byte("y"))
+	expectBufferContents(t, buffer, "R [a]\nE [Read(): ERROR!]\n")
+	expectNumber(t, 2, int(logged.Stats().Errors))
+	expectNumber(t, 1, int(logged.Stats().BytesWritten))
+
+	buffer.Reset()
+	logged.ResumeAfterError()
+	logged.Write([]byte("z"))
+	proxied.FailNextOperations = true
+	logged.Write([]byte("w"))
+	proxied.FailNextOperations = false
+	logged.Write([]byte("v"))
+	logged.Close()
+	expectBufferContents(t, buffer, "W [z]\nE [Write(): ERROR!]\nC\n")
+}
@@ -0,0 +1,281 @@
+package loggedio
+
+import (
+	"encoding/base64"
+	"encoding/hex"
+	"mime/quotedprintable"
+	"strings"
+	"sync/atomic"
+	"unicode/utf8"
+)
+
+// Encoding determines how read and write payloads are rendered by the
+// formatting proxy generators (StringToLog, HexToWriter, etc).
+type Encoding int32
+
+const (
+	// Render the payload as-is, interpreted as a string.
+	EncodingString Encoding = iota
+	// Render the payload as space separated hex bytes.
+	EncodingHex
+	// Render the payload as standard base64.
+	EncodingBase64
+	// Render the payload as a string, replacing non-printable bytes with '.'.
+	EncodingPrintable
+	// Render the payload as a string if it's mostly printable text (see
+	// LoggedIOProxy.TextThreshold), or as hex otherwise.
+	EncodingAuto
+	// Render the payload as a double quoted Go/C string literal, escaping
+	// non-printable bytes (for example "GET /\r\n\x00").
+	EncodingGoLiteral
+	// Render the payload as a string with each '%' doubled, for output that
+	// will itself be used as a format string later (for example by passing it
+	// on to log.Printf). Payloads are always passed to formats as arguments,
+	// never as the format itself, so this is only needed when the rendered
+	// output goes through another formatting step.
+	EncodingFormatSafe
+	// Render runs of printable bytes as double quoted strings and runs of
+	// other bytes as hex, in order (for example "GET " 00 01 " HTTP"). This
+	// keeps framed text protocols readable. Quotes and backslashes within
+	// the strings are escaped with a backslash.
+	EncodingSegmented
+	// Render the payload as a string if it's mostly printable text (see
+	// LoggedIOProxy.TextThreshold), or otherwise as a classic hex dump with
+	// offsets and an ASCII gutter, starting on a new line:
+	//
+	//	00000000  00 01 47 45 54                                    |..GET|
+	EncodingAutoDump
+	// Render the payload as quoted-printable (RFC 2045), as used by MIME
+	// email bodies (for example "a=3Db=FF").
+	EncodingQuotedPrintable
+)
+
+var encodingNames = map[Encoding]string{
+	EncodingString:          "String",
+	EncodingHex:             "Hex",
+	EncodingBase64:          "Base64",
+	EncodingPrintable:       "Printable",
+	EncodingAuto:            "Auto",
+	EncodingGoLiteral:       "GoLiteral",
+	EncodingFormatSafe:      "FormatSafe",
+	EncodingSegmented:       "Segmented",
+	EncodingAutoDump:        "AutoDump",
+	EncodingQuotedPrintable: "QuotedPrintable",
+}
+
+func (this Encoding) String() string {
+	if name, ok := encodingNames[this]; ok {
+		return name
+	}
+	return "Unknown"
+}
+
+func (this Encoding) encode(b []byte) string {
+	switch this {
+	case EncodingHex:
+		return toHex(b)
+	case EncodingBase64:
+		return base64.StdEncoding.EncodeToString(b)
+	case EncodingPrintable:
+		return toPrintable(b)
+	case EncodingAuto:
+		return encodeAuto(b, DefaultTextThreshold)
+	case EncodingGoLiteral:
+		return toGoLiteral(b)
+	case EncodingFormatSafe:
+		return strings.Replace(string(b), "%", "%%", -1)
+	case EncodingSegmented:
+		return toSegmented(b)
+	case EncodingAutoDump:
+		return encodeAutoDump(b, DefaultTextThreshold)
+	case EncodingQuotedPrintable:
+		return toQuotedPrintable(b)
+	default:
+		return string(b)
+	}
+}
+
+// LineEndingStyle determines how carriage returns and line feeds are rendered
+// in string encoded payloads (see LoggedIOProxy.LineEndings).
+type LineEndingStyle int
+
+const (
+	// Render '\r' and '\n' as they are.
+	LineEndingsAsIs LineEndingStyle = iota
+	// Render '\r' as "<CR>" and '\n' as "<LF>", so that each event stays on
+	// one line with its delimiters visible.
+	LineEndingsTokens
+	// Render '\r' as "<CR>" and '\n' as "<LF>" followed by a real newline, so
+	// that text protocols stay readable line by line.
+	LineEndingsTokensAndNewlines
+)
+
+var lineEndingReplacers = map[LineEndingStyle]*strings.Replacer{
+	LineEndingsTokens:            strings.NewReplacer("\r", "<CR>", "\n", "<LF>"),
+	LineEndingsTokensAndNewlines: strings.NewReplacer("\r", "<CR>", "\n", "<LF>\n"),
+}
+
+func (this LineEndingStyle) render(s string) string {
+	if replacer, ok := lineEndingReplacers[this]; ok {
+		return replacer.Replace(s)
+	}
+	return s
+}
+
+// SetEncoding changes how subsequent read and write payloads are rendered. This
+// is useful when a connection switches protocols mid-stream (for example an
+// HTTP upgrade to a binary protocol).
+//
+// The encoding only affects proxies built by the formatting proxy generators
+// (StringToLog, HexToLog, StringToWriter, HexToWriter). It's safe to call
+// concurrently with I/O. On a MixedToWriter proxy, it switches both directions
+// to the same encoding.
+func (this *LoggedIOProxy) SetEncoding(encoding Encoding) {
+	atomic.StoreInt32(&this.encoding, int32(encoding))
+	this.setFormat(func(formatter *textFormatter) { formatter.mixedEncodings = false })
+}
+
+// Encoding returns the encoding currently used to render payloads.
+func (this *LoggedIOProxy) Encoding() Encoding {
+	return Encoding(atomic.LoadInt32(&this.encoding))
+}
+
+func isPrintable(ch byte) bool {
+	return ch >= 0x20 && ch < 0x7f
+}
+
+// DefaultTextThreshold is the default fraction of a payload's bytes that must
+// be text for EncodingAuto to render it as a string.
+const DefaultTextThreshold = 0.85
+
+// encodeAuto renders b as a string if at least threshold of its bytes are
+// text, or as hex otherwise.
+func encodeAuto(b []byte, threshold float64) string {
+	if textFraction(b) >= threshold {
+		return string(b)
+	}
+	return toHex(b)
+}
+
+// encodeAutoDump renders b as a string if at least threshold of its bytes are
+// text, or as a hex dump otherwise.
+func encodeAutoDump(b []byte, threshold float64) string {
+	if textFraction(b) >= threshold {
+		return string(b)
+	}
+	return "\n" + strings.TrimSuffix(hex.Dump(b), "\n")
+}
+
+// textFraction returns the fraction of b's bytes that belong to valid UTF-8
+// characters other than control characters (tab, CR, and LF count as text).
+// An empty payload counts as all text.
+func textFraction(b []byte) float64 {
+	if len(b) == 0 {
+		return 1
+	}
+	textBytes := 0
+	for i := 0; i < len(b); {
+		ch, size := utf8.DecodeRune(b[i:])
+		isValid := ch != utf8.RuneError || size > 1
+		isControl := ch < 0x20 && ch != '\t' && ch != '\r' && ch != '\n' || ch == 0x7f
+		if isValid && !isControl {
+			textBytes += size
+		}
+		i += size
+	}
+	return float64(textBytes) / float64(len(b))
+}
+
+func toPrintable(b []byte) string {
+	result := make([]byte, len(b))
+	for i, ch := range b {
+		if isPrintable(ch) {
+			result[i] = ch
+		} else {
+			result[i] = '.'
+		}
+	}
+	return string(result)
+}
+
+func toGoLiteral(b []byte) string {
+	builder := strings.Builder{}
+	builder.WriteByte('"')
+	for _, ch := range b {
+		switch ch {
+		case '"', '\\':
+			builder.WriteByte('\\')
+			builder.WriteByte(ch)
+		case '\n':
+			builder.WriteString("\\n")
+		case '\r':
+			builder.WriteString("\\r")
+		case '\t':
+			builder.WriteString("\\t")
+		default:
+			if isPrintable(ch) {
+				builder.WriteByte(ch)
+			} else {
+				builder.WriteString("\\x")
+				builder.WriteByte(hexDigits[ch>>4])
+				builder.WriteByte(hexDigits[ch&15])
+			}
+		}
+	}
+	builder.WriteByte('"')
+	return builder.String()
+}
+
+func toSegmented(b []byte) string {
+	builder := strings.Builder{}
+	for i := 0; i < len(b); {
+		if builder.Len() > 0 {
+			builder.WriteByte(' ')
+		}
+		end := i
+		if isPrintable(b[i]) {
+			for end < len(b) && isPrintable(b[end]) {
+				end++
+			}
+			builder.WriteByte('"')
+			for _, ch := range b[i:end] {
+				if ch == '"' || ch == '\\' {
+					builder.WriteByte('\\')
+				}
+				builder.WriteByte(ch)
+			}
+			builder.WriteByte('"')
+		} else {
+			for end < len(b) && !isPrintable(b[end]) {
+				end++
+			}
+			builder.WriteString(toHex(b[i:end]))
+		}
+		i = end
+	}
+	return builder.String()
+}
+
+// wrapLines splits s into lines of at most lineLength bytes, separated by
+// terminator.
+func wrapLines(s string, lineLength int, terminator string) string {
+	if len(s) <= lineLength {
+		return s
+	}
+	builder := strings.Builder{}
+	for len(s) > lineLength {
+		builder.WriteString(s[:lineLength])
+		builder.WriteString(terminator)
+		s = s[lineLength:]
+	}
+	builder.WriteString(s)
+	return builder.String()
+}
+
+func toQuotedPrintable(b []byte) string {
+	builder := strings.Builder{}
+	writer := quotedprintable.NewWriter(&builder)
+	writer.Write(b)
+	writer.Close()
+	return builder.String()
+}
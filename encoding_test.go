@@ -0,0 +1,188 @@
+package loggedio
+
+import (
+	"bytes"
+	"fmt"
+	"log"
+	"os"
+	"testing"
+)
+
+func assertEncoding(t *testing.T, encoding Encoding, payload []byte, expected string) {
+	actual := encoding.encode(payload)
+	if actual != expected {
+		t.Errorf("%v: Expected \"%v\" but got \"%v\"", encoding, expected, actual)
+	}
+}
+
+func TestEncodings(t *testing.T) {
+	assertEncoding(t, EncodingString, []byte("ab\n"), "ab\n")
+	assertEncoding(t, EncodingHex, []byte("ab\n"), "61 62 0a")
+	assertEncoding(t, EncodingBase64, []byte("ab\n"), "YWIK")
+	assertEncoding(t, EncodingPrintable, []byte("ab\n\x00"), "ab..")
+	assertEncoding(t, EncodingAuto, []byte("ab\n"), "ab\n")
+	assertEncoding(t, EncodingAuto, []byte("ab\x00"), "61 62 00")
+	assertEncoding(t, EncodingGoLiteral, []byte("a\"\\\t\xff"), `"a\"\\\t\xff"`)
+	assertEncoding(t, EncodingSegmented, []byte("GET \x00\x01 HTTP"), `"GET " 00 01 " HTTP"`)
+	assertEncoding(t, EncodingSegmented, []byte("\r\n\"a\\\""), `0d 0a "\"a\\\""`)
+	assertEncoding(t, EncodingSegmented, []byte{}, "")
+	assertEncoding(t, EncodingQuotedPrintable, []byte("a=b\xff"), "a=3Db=FF")
+}
+
+func TestPayloadNotUsedAsFormat(t *testing.T) {
+	buffer := &bytes.Buffer{}
+	logged := StringToWriter(&MockIO{}, buffer, "R [%v]", "W [%v]", "E [%v: %v]", "C")
+	logged.Write([]byte("%s%n%%"))
+	expectBufferContents(t, buffer, "W [%s%n%%]")
+
+	// Output that goes through a second formatting step must be escaped.
+	buffer.Reset()
+	logged.SetEncoding(EncodingFormatSafe)
+	logged.Write([]byte("%s%n%%"))
+	expectBufferContents(t, buffer, "W [%%s%%n%%%%]")
+	expectString(t, "W [%s%n%%]", fmt.Sprintf(buffer.String()))
+}
+
+func TestGoLiteralToWriter(t *testing.T) {
+	buffer := &bytes.Buffer{}
+	logged := GoLiteralToWriter(&MockIO{}, buffer, "R [%v]", "W [%v]", "E [%v: %v]", "C")
+	logged.Write([]byte("GET /\r\n\x01"))
+	expectBufferContents(t, buffer, `W ["GET /\r\n\x01"]`)
+}
+
+func TestSetEncoding(t *testing.T) {
+	proxied := &MockIO{}
+	buffer := &bytes.Buffer{}
+	logged := StringToWriter(proxied, buffer, "R [%v]", "W [%v]", "E [%v: %v]", "C")
+
+	logged.Read(make([]byte, 3))
+	expectBufferContents(t, buffer, "R [abc]")
+
+	buffer.Reset()
+	logged.SetEncoding(EncodingHex)
+	logged.Read(make([]byte, 3))
+	expectBufferContents(t, buffer, "R [61 62 63]")
+}
+
+func TestWriteFormatIndependentOfReadFormat(t *testing.T) {
+	buffer := &bytes.Buffer{}
+	logged := StringToWriter(&MockIO{}, buffer, "", "W [%v]", "", "")
+	logged.Write([]byte("test"))
+	expectBufferContents(t, buffer, "W [test]")
+
+	buffer.Reset()
+	logged = HexToWriter(&MockIO{}, buffer, "", "W [%v]", "", "")
+	logged.Write([]byte{1, 2})
+	expectBufferContents(t, buffer, "W [01 02]")
+
+	buffer.Reset()
+	log.SetOutput(buffer)
+	log.SetFlags(0)
+	defer log.SetOutput(os.Stderr)
+	defer log.SetFlags(log.LstdFlags)
+	logged = HexToLog(&MockIO{}, "", "W [%v]", "", "")
+	logged.Write([]byte{1, 2})
+	expectBufferContents(t, buffer, "W [01 02]\n")
+}
+
+func TestMixedToWriter(t *testing.T) {
+	buffer := &bytes.Buffer{}
+	logged := MixedToWriter(&MockIO{}, buffer, EncodingHex, EncodingString, "R [%v]\n", "W [%v]\n", "E [%v: %v]\n", "C\n")
+
+	logged.Read(make([]byte, 3))
+	logged.Write([]byte("xyz"))
+	expectBufferContents(t, buffer, "R [61 62 63]\nW [xyz]\n")
+
+	buffer.Reset()
+	logged.SetEncoding(EncodingString)
+	logged.Read(make([]byte, 3))
+	expectBufferContents(t, buffer, "R [abc]\n")
+}
+
+func TestLineEndings(t *testing.T) {
+	buffer := &bytes.Buffer{}
+	logged := StringToWriter(&MockIO{}, buffer, "R [%v]", "W [%v]", "E [%v: %v]", "C")
+	logged.LineEndings = LineEndingsTokens
+	logged.Write([]byte("GET /\r\n"))
+	expectBufferContents(t, buffer, "W [GET /<CR><LF>]")
+
+	buffer.Reset()
+	logged.LineEndings = LineEndingsTokensAndNewlines
+	logged.Write([]byte("a\r\nb"))
+	expectBufferContents(t, buffer, "W [a<CR><LF>\nb]")
+
+	buffer.Reset()
+	logged.SetEncoding(EncodingHex)
+	logged.Write([]byte("\r\n"))
+	expectBufferContents(t, buffer, "W [0d 0a]")
+}
+
+func TestTextThreshold(t *testing.T) {
+	buffer := &bytes.Buffer{}
+	logged := StringToWriter(&MockIO{}, buffer, "R [%v]\n", "W [%v]\n", "E [%v: %v]\n", "C\n")
+	logged.SetEncoding(EncodingAuto)
+
+	// 9 of 10 bytes are text.
+	logged.Write([]byte("abcdefghi\x00"))
+	// 2 of 4 bytes are text.
+	logged.Write([]byte("ab\x00\x01"))
+	expectBufferContents(t, buffer, "W [abcdefghi\x00]\nW [61 62 00 01]\n")
+
+	buffer.Reset()
+	logged.TextThreshold = 1
+	logged.Write([]byte("abcdefghi\x00"))
+	logged.TextThreshold = 0.5
+	logged.Write([]byte("ab\x00\x01"))
+	expectBufferContents(t, buffer, "W [61 62 63 64 65 66 67 68 69 00]\nW [ab\x00\x01]\n")
+}
+
+func TestBase64LineLength(t *testing.T) {
+	buffer := &bytes.Buffer{}
+	logged := StringToWriter(&MockIO{}, buffer, "R [%v]\n", "W [%v]\n", "E [%v: %v]\n", "C\n")
+	logged.SetEncoding(EncodingBase64)
+	payload := []byte("The quick brown fox jumps over the lazy dog")
+
+	logged.Write(payload)
+	expectBufferContents(t, buffer, "W [VGhlIHF1aWNrIGJyb3duIGZveCBqdW1wcyBvdmVyIHRoZSBsYXp5IGRvZw==]\n")
+
+	buffer.Reset()
+	logged.Base64LineLength = 20
+	logged.Write(payload)
+	expectBufferContents(t, buffer, "W [VGhlIHF1aWNrIGJyb3du\nIGZveCBqdW1wcyBvdmVy\nIHRoZSBsYXp5IGRvZw==]\n")
+
+	buffer.Reset()
+	logged.Base64LineTerminator = "\r\n"
+	logged.Write(payload[:15])
+	expectBufferContents(t, buffer, "W [VGhlIHF1aWNrIGJyb3du]\n")
+	buffer.Reset()
+	logged.Write(payload[:16])
+	expectBufferContents(t, buffer, "W [VGhlIHF1aWNrIGJyb3du\r\nIA==]\n")
+}
+
+func TestAutoDump(t *testing.T) {
+	buffer := &bytes.Buffer{}
+	logged := StringToWriter(&MockIO{}, buffer, "R [%v]\n", "W [%v]\n", "E [%v: %v]\n", "C\n")
+	logged.SetEncoding(EncodingAutoDump)
+
+	logged.Write([]byte("GET / HTTP/1.1\r\n"))
+	expectBufferContents(t, buffer, "W [GET / HTTP/1.1\r\n]\n")
+
+	buffer.Reset()
+	logged.Write([]byte("\x00\x01\x02\x03GET / HTTP/1.1\xff"))
+	expectBufferContents(t, buffer, "W [\n"+
+		"00000000  00 01 02 03 47 45 54 20  2f 20 48 54 54 50 2f 31  |....GET / HTTP/1|\n"+
+		"00000010  2e 31 ff                                          |.1.|]\n")
+
+	buffer.Reset()
+	logged.TextThreshold = 0.5
+	logged.Write([]byte("\x00\x01\x02\x03GET / HTTP/1.1\xff"))
+	expectBufferContents(t, buffer, "W [\x00\x01\x02\x03GET / HTTP/1.1\xff]\n")
+}
+
+func TestQuotedPrintable(t *testing.T) {
+	buffer := &bytes.Buffer{}
+	logged := StringToWriter(&MockIO{}, buffer, "R [%v]\n", "W [%v]\n", "E [%v: %v]\n", "C\n")
+	logged.SetEncoding(EncodingQuotedPrintable)
+	logged.Write([]byte("Subject: caf\xc3\xa9 =?\r\n"))
+	expectBufferContents(t, buffer, "W [Subject: caf=C3=A9 =3D?\r\n]\n")
+}
@@ -0,0 +1,54 @@
+package loggedio
+
+import (
+	"time"
+)
+
+// ErrorEvent is an error reported by a proxy, as returned by RecentErrors().
+type ErrorEvent struct {
+	// Where the error occurred (for example "Read()").
+	Location string
+	Err      error
+	// When the error was reported, according to the proxy's clock.
+	Time time.Time
+}
+
+// errorHistory is a ring buffer of the most recent errors.
+type errorHistory struct {
+	events []ErrorEvent
+	// The index of the oldest event once the buffer is full.
+	next int
+}
+
+func (this *errorHistory) add(capacity int, event ErrorEvent) {
+	if capacity <= 0 {
+		return
+	}
+	if len(this.events) < capacity {
+		this.events = append(this.events, event)
+		return
+	}
+	this.events[this.next] = event
+	this.next = (this.next + 1) % len(this.events)
+}
+
+// newest returns up to n of the most recent events, oldest first.
+func (this *errorHistory) newest(n int) []ErrorEvent {
+	if n > len(this.events) {
+		n = len(this.events)
+	}
+	result := make([]ErrorEvent, 0, n)
+	for i := len(this.events) - n; i < len(this.events); i++ {
+		result = append(result, this.events[(this.next+i)%len(this.events)])
+	}
+	return result
+}
+
+// RecentErrors returns up to n of the most recently reported errors, oldest
+// first. Only the last RecentErrorsCapacity errors are kept, so nothing is
+// returned unless that option is set.
+func (this *LoggedIOProxy) RecentErrors(n int) []ErrorEvent {
+	this.mutex.Lock()
+	defer this.mutex.Unlock()
+	return this.recentErrors.newest(n)
+}
@@ -0,0 +1,46 @@
+package loggedio
+
+import (
+	"testing"
+	"time"
+)
+
+func TestRecentErrors(t *testing.T) {
+	proxied := &MockIO{}
+	clock := newMockClock()
+	logged := StringToWriter(proxied, &NullWriter{}, "R [%v]", "W [%v]", "E [%v: %v]", "C")
+	logged.SetClock(clock)
+	start := clock.Now()
+
+	proxied.FailNextOperations = true
+	logged.Read(make([]byte, 3))
+	expectNumber(t, 0, len(logged.RecentErrors(2)))
+
+	logged.RecentErrorsCapacity = 2
+	var errors []error
+	for i := 0; i < 3; i++ {
+		clock.Advance(time.Second)
+		proxied.FailNextOperations = true
+		_, err := logged.Write([]byte("test"))
+		errors = append(errors, err)
+	}
+
+	recent := logged.RecentErrors(5)
+	expectNumber(t, 2, len(recent))
+	for i, event := range recent {
+		expectString(t, "Write()", event.Location)
+		if event.Err != errors[i+1] {
+			t.Errorf("Expected error %v to be %v but got %v", i, errors[i+1], event.Err)
+		}
+		expected := start.Add(time.Duration(i+2) * time.Second)
+		if !event.Time.Equal(expected) {
+			t.Errorf("Expected error %v at %v but got %v", i, expected, event.Time)
+		}
+	}
+
+	recent = logged.RecentErrors(1)
+	expectNumber(t, 1, len(recent))
+	if recent[0].Err != errors[2] {
+		t.Errorf("Expected the most recent error %v but got %v", errors[2], recent[0].Err)
+	}
+}
@@ -0,0 +1,59 @@
+package loggedio
+
+import (
+	"fmt"
+	"reflect"
+	"runtime"
+	"strings"
+)
+
+// The most stack frames included in an error's stack trace.
+const maxErrorStackFrames = 32
+
+// The function name prefix of LoggedIOProxy methods, such as
+// "github.com/kstenerud/go-loggedio.(*LoggedIOProxy).".
+var proxyMethodPrefix = strings.TrimSuffix(
+	runtime.FuncForPC(reflect.ValueOf((*LoggedIOProxy).Unwrap).Pointer()).Name(), "Unwrap")
+
+// captureErrorStack returns the stack of the calling goroutine, starting at
+// the caller of the proxy method that reported the error. Frames belonging to
+// the runtime and the proxy's own reporting are omitted.
+func captureErrorStack() string {
+	pcs := make([]uintptr, 64)
+	pcs = pcs[:runtime.Callers(2, pcs)]
+	frames := runtime.CallersFrames(pcs)
+
+	var collected []runtime.Frame
+	for {
+		frame, more := frames.Next()
+		if strings.HasPrefix(frame.Function, proxyMethodPrefix) {
+			// Everything so far was internal to the proxy.
+			collected = collected[:0]
+		} else if !strings.HasPrefix(frame.Function, "runtime.") {
+			collected = append(collected, frame)
+		}
+		if !more {
+			break
+		}
+	}
+
+	builder := strings.Builder{}
+	for i, frame := range collected {
+		if i == maxErrorStackFrames {
+			fmt.Fprintf(&builder, "\t... %v more\n", len(collected)-i)
+			break
+		}
+		fmt.Fprintf(&builder, "\t%v\n\t\t%v:%v\n", frame.Function, frame.File, frame.Line)
+	}
+	return builder.String()
+}
+
+// reportErrorStack reports the current stack for an error at location, if
+// CaptureErrorStack calls for it. isFirstError is true if this is the first
+// error the proxy has reported.
+func (this *LoggedIOProxy) reportErrorStack(location string, isFirstError bool) {
+	if !this.CaptureErrorStack || !(isFirstError || this.CaptureAllErrorStacks) {
+		return
+	}
+	this.reportNotify(fmt.Sprintf("LoggedIO: Stack for error at %v:\n%v", location, captureErrorStack()))
+}
@@ -0,0 +1,36 @@
+package loggedio
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestCaptureErrorStack(t *testing.T) {
+	buffer := &bytes.Buffer{}
+	logged := StringToWriter(&MockIO{FailNextOperations: true}, buffer, "R [%v]\n", "W [%v]\n", "E [%v: %v]\n", "C\n")
+	logged.CaptureErrorStack = true
+
+	logged.Write([]byte("abc"))
+	output := buffer.String()
+	prefix := "E [Write(): ERROR!]\nLoggedIO: Stack for error at Write():\n" +
+		"\tgithub.com/kstenerud/go-loggedio.TestCaptureErrorStack\n"
+	if !strings.HasPrefix(output, prefix) {
+		t.Fatalf("Expected output to start with %q but got %q", prefix, output)
+	}
+	if strings.Contains(output, "(*LoggedIOProxy)") {
+		t.Errorf("Expected proxy frames to be trimmed from %q", output)
+	}
+
+	// Only the first error gets a stack by default.
+	buffer.Reset()
+	logged.Write([]byte("abc"))
+	expectBufferContents(t, buffer, "E [Write(): ERROR!]\n")
+
+	buffer.Reset()
+	logged.CaptureAllErrorStacks = true
+	logged.Write([]byte("abc"))
+	if !strings.Contains(buffer.String(), "TestCaptureErrorStack") {
+		t.Errorf("Expected a stack in %q", buffer.String())
+	}
+}
@@ -0,0 +1,87 @@
+package loggedio
+
+import (
+	"time"
+)
+
+// EventType identifies the kind of event being reported.
+type EventType int
+
+const (
+	EventRead EventType = iota
+	EventWrite
+	EventError
+	EventClose
+	EventNotify
+	eventTypeCount
+)
+
+var eventTypeNames = [eventTypeCount]string{
+	EventRead:   "read",
+	EventWrite:  "write",
+	EventError:  "error",
+	EventClose:  "close",
+	EventNotify: "notify",
+}
+
+func (this EventType) String() string {
+	if this >= 0 && this < eventTypeCount {
+		return eventTypeNames[this]
+	}
+	return "unknown"
+}
+
+// Event describes a single reported event. Which fields are set depends on
+// the event type.
+type Event struct {
+	Type EventType
+
+	// The event's sequence number, starting at 1. All event types share one
+	// sequence unless the proxy's SeparateSequences option is set. The number
+	// is assigned as the proxy reports the event, before it's delivered, so
+	// every sink and output sees the same number for the same event. A gap
+	// in one output's numbering means that the event was dropped, or that
+	// the output chose not to show it (for example because of an empty
+	// format).
+	Sequence uint64
+
+	// For read and write events when the proxy's TrackCompletionOrder option
+	// is set, the order in which the event's I/O call completed, starting at 1
+	// and shared by both directions. With ReportBeforeWrite, a write's number
+	// is taken when it's reported (before the write is made). 0 means that
+	// completion order wasn't tracked.
+	Completion uint64
+
+	// The payload of a read or write event.
+	Data []byte
+
+	// For read events when the proxy's ReportWriteToReadLatency option is set,
+	// FollowsWrite is true if this is the first read since a write, and
+	// SinceWrite holds the time elapsed since that write completed.
+	FollowsWrite bool
+	SinceWrite   time.Duration
+
+	// The read and write deadlines in effect when the event was reported, as
+	// last set via the proxy's SetDeadline(), SetReadDeadline(), or
+	// SetWriteDeadline() methods. A zero time means no deadline.
+	ReadDeadline  time.Time
+	WriteDeadline time.Time
+
+	// The location and error of an error event. Also set for read and write
+	// events when the proxy's CombineDataAndError option is set and the I/O
+	// call returned an error along with the data.
+	Location string
+	Err      error
+
+	// For error events, true if the error was already attached to the
+	// preceding read or write event (see CombineDataAndError). Outputs that
+	// show such errors with the data skip these events.
+	ReportedWithData bool
+
+	// The message of a notify event.
+	Message string
+
+	// The phase of the connection the event occurred in, if the proxy's
+	// HandshakeBytes or HandshakeDuration option is set.
+	Phase Phase
+}
@@ -0,0 +1,171 @@
+package loggedio
+
+import (
+	"bytes"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestSequenceJSON(t *testing.T) {
+	proxied := &MockIO{}
+	buffer := &bytes.Buffer{}
+	logged := JSONToWriter(proxied, buffer)
+
+	logged.Read(make([]byte, 3))
+	logged.Write([]byte("test"))
+	logged.Read(make([]byte, 3))
+
+	events := decodeJSONEvents(t, buffer)
+	expectNumber(t, 3, len(events))
+	for i, event := range events {
+		expectNumber(t, i+1, int(event.Sequence))
+	}
+}
+
+func TestSequenceInText(t *testing.T) {
+	proxied := &MockIO{}
+	buffer := &bytes.Buffer{}
+	logged := StringToWriter(proxied, buffer, "%v R [%v]\n", "%v W [%v]\n", "%v E [%v: %v]\n", "C\n")
+	logged.SequenceInText = true
+
+	logged.Read(make([]byte, 3))
+	logged.Write([]byte("test"))
+	logged.Read(make([]byte, 3))
+	expectBufferContents(t, buffer, "1 R [abc]\n2 W [test]\n3 R [abc]\n")
+}
+
+func TestSequenceCountsUnprintedEvents(t *testing.T) {
+	proxied := &MockIO{}
+	buffer := &bytes.Buffer{}
+	logged := StringToWriter(proxied, buffer, "", "%v W [%v]\n", "%v E [%v: %v]\n", "C\n")
+	logged.SequenceInText = true
+
+	logged.Read(make([]byte, 3))
+	logged.Write([]byte("test"))
+	logged.Close()
+	logged.Write([]byte("test"))
+	expectBufferContents(t, buffer, "2 W [test]\nC\n4 W [test]\n")
+}
+
+func TestSequenceInSinks(t *testing.T) {
+	proxied := &MockIO{}
+	buffer := &bytes.Buffer{}
+	logged := JSONToWriter(proxied, buffer)
+	sink := &MemorySink{}
+	logged.AddSink(sink)
+
+	logged.Read(make([]byte, 3))
+	logged.Write([]byte("test"))
+	logged.Close()
+
+	events := sink.Events()
+	expectNumber(t, 3, len(events))
+	for i, event := range events {
+		expectNumber(t, i+1, int(event.Sequence))
+	}
+	jsonEvents := decodeJSONEvents(t, buffer)
+	expectNumber(t, len(events), len(jsonEvents))
+	for i := range jsonEvents {
+		if i < len(events) {
+			expectNumber(t, int(events[i].Sequence), int(jsonEvents[i].Sequence))
+		}
+	}
+}
+
+func TestSeparateSequences(t *testing.T) {
+	proxied := &MockIO{}
+	buffer := &bytes.Buffer{}
+	logged := StringToWriter(proxied, buffer, "%v R [%v]\n", "%v W [%v]\n", "%v E [%v: %v]\n", "C\n")
+	logged.SequenceInText = true
+	logged.SeparateSequences = true
+
+	logged.Read(make([]byte, 3))
+	logged.Write([]byte("test"))
+	logged.Read(make([]byte, 3))
+	expectBufferContents(t, buffer, "1 R [abc]\n1 W [test]\n2 R [abc]\n")
+}
+
+func TestWriteToReadLatency(t *testing.T) {
+	proxied := &MockIO{}
+	buffer := &bytes.Buffer{}
+	clock := newMockClock()
+	logged := StringToWriter(proxied, buffer, "R %v[%v]\n", "W [%v]\n", "E [%v: %v]\n", "C\n")
+	logged.ReportWriteToReadLatency = true
+	logged.SetClock(clock)
+
+	logged.Write([]byte("test"))
+	clock.Advance(2300 * time.Microsecond)
+	logged.Read(make([]byte, 3))
+	clock.Advance(time.Second)
+	logged.Read(make([]byte, 3))
+	expectBufferContents(t, buffer, "W [test]\nR (+2.3ms since write) [abc]\nR [abc]\n")
+}
+
+func TestEventDeadlines(t *testing.T) {
+	proxy, sink := NewWithMemorySink(&MockIO{})
+	readDeadline := time.Date(2020, 1, 1, 0, 0, 5, 0, time.UTC)
+	bothDeadline := time.Date(2020, 1, 1, 0, 0, 10, 0, time.UTC)
+
+	proxy.Read(make([]byte, 1))
+	expectNoError(t, proxy.SetReadDeadline(readDeadline))
+	proxy.Read(make([]byte, 1))
+	expectNoError(t, proxy.SetDeadline(bothDeadline))
+	proxy.Write([]byte("a"))
+
+	events := sink.Events()
+	expectNumber(t, 3, len(events))
+	if !events[0].ReadDeadline.IsZero() || !events[0].WriteDeadline.IsZero() {
+		t.Errorf("Expected no deadlines but got %v", events[0])
+	}
+	if !events[1].ReadDeadline.Equal(readDeadline) || !events[1].WriteDeadline.IsZero() {
+		t.Errorf("Expected read deadline %v but got %v", readDeadline, events[1])
+	}
+	if !events[2].ReadDeadline.Equal(bothDeadline) || !events[2].WriteDeadline.Equal(bothDeadline) {
+		t.Errorf("Expected both deadlines %v but got %v", bothDeadline, events[2])
+	}
+}
+
+func TestCompletionOrder(t *testing.T) {
+	entered := make(chan bool)
+	release := make(chan bool)
+	var mutex sync.Mutex
+	var reported []uint64
+	proxy := newProxy(&MockIO{}, func(event *Event) {
+		if event.Completion == 1 {
+			// Hold up the first event's report until the second is reported.
+			close(entered)
+			<-release
+		}
+		mutex.Lock()
+		reported = append(reported, event.Completion)
+		mutex.Unlock()
+	})
+	proxy.TrackCompletionOrder = true
+
+	done := make(chan bool)
+	go func() {
+		proxy.Read(make([]byte, 1))
+		close(done)
+	}()
+	<-entered
+	proxy.Write([]byte("a"))
+	close(release)
+	<-done
+
+	expectNumber(t, 2, len(reported))
+	expectNumber(t, 2, int(reported[0]))
+	expectNumber(t, 1, int(reported[1]))
+}
+
+func TestCompletionOrderJSON(t *testing.T) {
+	buffer := &bytes.Buffer{}
+	logged := JSONToWriter(&MockIO{}, buffer)
+	logged.TrackCompletionOrder = true
+	logged.Write([]byte("a"))
+	logged.Read(make([]byte, 1))
+	events := decodeJSONEvents(t, buffer)
+	expectNumber(t, 2, len(events))
+	expectNumber(t, 1, int(events[0].Completion))
+	expectNumber(t, 2, int(events[1].Completion))
+}
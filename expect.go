@@ -0,0 +1,74 @@
+package loggedio
+
+import (
+	"fmt"
+	"io"
+)
+
+// MismatchError describes where a read stream first diverged from the
+// expected data. See NewExpectingReader().
+type MismatchError struct {
+	// The offset in the stream of the first differing byte.
+	Offset int64
+	// The expected bytes from Offset on (up to the length of Actual), or empty
+	// if the stream continued past the end of the expected data.
+	Expected []byte
+	// The bytes actually read from Offset on, up to the end of that read.
+	Actual []byte
+}
+
+func (this *MismatchError) Error() string {
+	return fmt.Sprintf("stream differs from expected at offset %v: expected [%v] but got [%v]",
+		this.Offset, toHex(this.Expected), toHex(this.Actual))
+}
+
+// NewExpectingReader wraps r in a logged I/O proxy built by generate, and
+// compares everything read through it against expected. The moment the stream
+// diverges (including by continuing past the end of expected), a
+// *MismatchError is reported as an error event with the location
+// "LoggedIO expect". Only the first mismatch is reported, and the data read is
+// still returned as normal.
+func NewExpectingReader(r io.Reader, expected []byte, generate ProxyGenerator) io.Reader {
+	return &expectingReader{
+		proxy:    generate(r),
+		expected: expected,
+	}
+}
+
+type expectingReader struct {
+	proxy      *LoggedIOProxy
+	expected   []byte
+	offset     int64
+	mismatched bool
+}
+
+func (this *expectingReader) Read(b []byte) (n int, err error) {
+	n, err = this.proxy.Read(b)
+	if !this.mismatched {
+		this.compare(b[:n])
+	}
+	this.offset += int64(n)
+	return
+}
+
+func (this *expectingReader) compare(actual []byte) {
+	var expected []byte
+	if this.offset < int64(len(this.expected)) {
+		expected = this.expected[this.offset:]
+	}
+	for i, ch := range actual {
+		if i >= len(expected) || expected[i] != ch {
+			end := len(actual)
+			if end > len(expected) {
+				end = len(expected)
+			}
+			this.mismatched = true
+			this.proxy.reportError("LoggedIO expect", &MismatchError{
+				Offset:   this.offset + int64(i),
+				Expected: append([]byte(nil), expected[i:end]...),
+				Actual:   append([]byte(nil), actual[i:]...),
+			})
+			return
+		}
+	}
+}
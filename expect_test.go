@@ -0,0 +1,65 @@
+package loggedio
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+)
+
+func newExpectingTest(contents, expected string) (*bytes.Buffer, *MemorySink, func(n int)) {
+	buffer := &bytes.Buffer{}
+	var sink *MemorySink
+	r := NewExpectingReader(&MockBody{contents: []byte(contents)}, []byte(expected),
+		func(o interface{}) *LoggedIOProxy {
+			proxy := StringToWriter(o, buffer, "R [%v]", "W [%v]", "E [%v: %v]", "C")
+			proxy.SuppressEOF = true
+			sink = &MemorySink{}
+			proxy.AddSink(sink)
+			return proxy
+		})
+	return buffer, sink, func(n int) { r.Read(make([]byte, n)) }
+}
+
+func expectMismatch(t *testing.T, sink *MemorySink) *MismatchError {
+	errs := sink.Errors()
+	if len(errs) != 1 {
+		t.Fatalf("Expected 1 mismatch but got %v", errs)
+	}
+	var mismatch *MismatchError
+	if !errors.As(errs[0], &mismatch) {
+		t.Fatalf("Expected a MismatchError but got %v", errs[0])
+	}
+	return mismatch
+}
+
+func TestExpectingReader(t *testing.T) {
+	buffer, sink, read := newExpectingTest("abcXefgh", "abcdefgh")
+	read(2)
+	read(4)
+	read(2)
+
+	mismatch := expectMismatch(t, sink)
+	expectNumber(t, 3, int(mismatch.Offset))
+	expectString(t, "def", string(mismatch.Expected))
+	expectString(t, "Xef", string(mismatch.Actual))
+	expectBufferContents(t, buffer, "R [ab]R [cXef]"+
+		"E [LoggedIO expect: stream differs from expected at offset 3: expected [64 65 66] but got [58 65 66]]"+
+		"R [gh]")
+}
+
+func TestExpectingReaderTooLong(t *testing.T) {
+	_, sink, read := newExpectingTest("abcd", "abc")
+	read(10)
+
+	mismatch := expectMismatch(t, sink)
+	expectNumber(t, 3, int(mismatch.Offset))
+	expectString(t, "", string(mismatch.Expected))
+	expectString(t, "d", string(mismatch.Actual))
+}
+
+func TestExpectingReaderMatch(t *testing.T) {
+	_, sink, read := newExpectingTest("abcd", "abcd")
+	read(3)
+	read(3)
+	expectNumber(t, 0, len(sink.Errors()))
+}
@@ -0,0 +1,45 @@
+package loggedio
+
+import (
+	"fmt"
+	"os"
+)
+
+// LoggedFile is a logged I/O proxy around an *os.File, adding Seek() and the
+// file's identity. See NewFile().
+type LoggedFile struct {
+	*LoggedIOProxy
+	file *os.File
+}
+
+// NewFile wraps f in a logged I/O proxy built by generate, and labels the
+// proxy with the file's descriptor and name (for example "fd=7 /tmp/x") so
+// that events from many open files can be told apart (see the Label option).
+//
+// Note: This calls f.Fd(), which puts the file into blocking mode.
+func NewFile(f *os.File, generate ProxyGenerator) *LoggedFile {
+	proxy := generate(f)
+	proxy.Label = fmt.Sprintf("fd=%v %v", f.Fd(), f.Name())
+	return &LoggedFile{
+		LoggedIOProxy: proxy,
+		file:          f,
+	}
+}
+
+// File returns the proxied file.
+func (this *LoggedFile) File() *os.File {
+	return this.file
+}
+
+// Name returns the proxied file's name.
+func (this *LoggedFile) Name() string {
+	return this.file.Name()
+}
+
+func (this *LoggedFile) Seek(offset int64, whence int) (ret int64, err error) {
+	ret, err = this.file.Seek(offset, whence)
+	if err != nil {
+		this.reportError(this.locationAfterClose("Seek()"), err)
+	}
+	return
+}
@@ -0,0 +1,39 @@
+package loggedio
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestNewFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "data.txt")
+	expectNoError(t, os.WriteFile(path, []byte("hello"), 0644))
+	f, err := os.Open(path)
+	expectNoError(t, err)
+
+	buffer := &bytes.Buffer{}
+	logged := NewFile(f, func(o interface{}) *LoggedIOProxy {
+		return StringToWriter(o, buffer, "R [%v]\n", "W [%v]\n", "E [%v: %v]\n", "C\n")
+	})
+	label := fmt.Sprintf("fd=%v %v", f.Fd(), path)
+
+	logged.Read(make([]byte, 3))
+	position, err := logged.Seek(0, io.SeekStart)
+	expectNoError(t, err)
+	expectNumber(t, 0, int(position))
+	logged.Read(make([]byte, 5))
+	logged.Close()
+	expectBufferContents(t, buffer, ""+
+		label+" R [hel]\n"+
+		label+" R [hello]\n"+
+		label+" C\n")
+
+	buffer.Reset()
+	_, err = logged.Seek(0, io.SeekStart)
+	expectError(t, err)
+	expectBufferContents(t, buffer, label+" E [Seek() after close: seek "+path+": file already closed]\n")
+}
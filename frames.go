@@ -0,0 +1,76 @@
+package loggedio
+
+import (
+	"fmt"
+	"io"
+	"sync"
+)
+
+// hexFramer accumulates one direction's payloads into fixed-size frames.
+type hexFramer struct {
+	pending    []byte
+	frameIndex int
+}
+
+// HexFramesToWriter creates a logged I/O proxy that writes reads and writes
+// to writer as hex, split into fixed-size frames of frameSize bytes regardless
+// of how the data was chunked by the I/O calls. Each direction is framed and
+// numbered separately, one frame per line:
+//
+//	read frame 0: 01 02 03 04
+//	read frame 1: 05 06 07 08
+//
+// A partial frame is held until it's complete, or until the proxy is closed,
+// at which point it's written as is. Errors are written as
+// "error <location>: <error>", closes as "close", and notifications as-is.
+//
+// The direction names can be changed via the proxy's DirectionLabels field.
+func HexFramesToWriter(proxiedObject interface{}, writer io.Writer, frameSize int) *LoggedIOProxy {
+	if frameSize <= 0 {
+		panic(fmt.Errorf("LoggedIO: Invalid frame size %v", frameSize))
+	}
+	target := newReportTarget(writer)
+	var mutex sync.Mutex
+	var readFramer, writeFramer hexFramer
+	var this *LoggedIOProxy
+
+	writeFrame := func(label string, framer *hexFramer, frame []byte) {
+		fmt.Fprintf(target, "%v frame %v: %v\n", label, framer.frameIndex, toHex(frame))
+		framer.frameIndex++
+	}
+	addData := func(label string, framer *hexFramer, b []byte) {
+		framer.pending = append(framer.pending, b...)
+		for len(framer.pending) >= frameSize {
+			writeFrame(label, framer, framer.pending[:frameSize])
+			framer.pending = framer.pending[frameSize:]
+		}
+		framer.pending = append([]byte(nil), framer.pending...)
+	}
+	flush := func(label string, framer *hexFramer) {
+		if len(framer.pending) > 0 {
+			writeFrame(label, framer, framer.pending)
+			framer.pending = nil
+		}
+	}
+
+	this = newProxy(proxiedObject, func(event *Event) {
+		mutex.Lock()
+		defer mutex.Unlock()
+		switch event.Type {
+		case EventRead:
+			addData(this.DirectionLabels.Read, &readFramer, event.Data)
+		case EventWrite:
+			addData(this.DirectionLabels.Write, &writeFramer, event.Data)
+		case EventError:
+			fmt.Fprintf(target, "error %v: %v\n", event.Location, event.Err)
+		case EventClose:
+			flush(this.DirectionLabels.Read, &readFramer)
+			flush(this.DirectionLabels.Write, &writeFramer)
+			fmt.Fprintf(target, "close\n")
+		case EventNotify:
+			io.WriteString(target, event.Message)
+		}
+	})
+	this.target = target
+	return this
+}
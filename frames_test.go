@@ -0,0 +1,37 @@
+package loggedio
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestHexFrames(t *testing.T) {
+	buffer := &bytes.Buffer{}
+	logged := HexFramesToWriter(&MockIO{}, buffer, 4)
+
+	logged.Read(make([]byte, 3))
+	expectBufferContents(t, buffer, "")
+	logged.Read(make([]byte, 7))
+	expectBufferContents(t, buffer, ""+
+		"read frame 0: 61 62 63 61\n"+
+		"read frame 1: 62 63 64 65\n")
+
+	buffer.Reset()
+	logged.Close()
+	expectBufferContents(t, buffer, ""+
+		"read frame 2: 66 67\n"+
+		"close\n")
+}
+
+func TestHexFramesSeparateDirections(t *testing.T) {
+	buffer := &bytes.Buffer{}
+	logged := HexFramesToWriter(&MockIO{}, buffer, 2)
+
+	logged.Write([]byte("abc"))
+	logged.Read(make([]byte, 2))
+	logged.Write([]byte("d"))
+	expectBufferContents(t, buffer, ""+
+		"write frame 0: 61 62\n"+
+		"read frame 0: 61 62\n"+
+		"write frame 1: 63 64\n")
+}
@@ -0,0 +1,51 @@
+package loggedio
+
+import (
+	"sync"
+)
+
+// OnReadBytes registers a framing callback that turns the proxy into a framing
+// tap for a protocol decoder. After every read, the callback is called with all
+// read bytes that haven't been consumed yet, and returns how many bytes (from
+// the start) it consumed. The callback is called repeatedly until it consumes
+// nothing or there are no bytes left. Unconsumed bytes are retained and
+// prepended to the next read's bytes.
+//
+// The slice passed to the callback is only valid for the duration of the call.
+func (this *LoggedIOProxy) OnReadBytes(callback func(b []byte) (consumed int)) {
+	this.readFramer.setCallback(callback)
+}
+
+type readFramer struct {
+	mutex    sync.Mutex
+	callback func(b []byte) (consumed int)
+	pending  []byte
+}
+
+func (this *readFramer) setCallback(callback func(b []byte) (consumed int)) {
+	this.mutex.Lock()
+	defer this.mutex.Unlock()
+	this.callback = callback
+	this.pending = nil
+}
+
+func (this *readFramer) feed(b []byte) {
+	this.mutex.Lock()
+	defer this.mutex.Unlock()
+	if this.callback == nil {
+		return
+	}
+
+	buffer := append(this.pending, b...)
+	for len(buffer) > 0 {
+		consumed := this.callback(buffer)
+		if consumed <= 0 {
+			break
+		}
+		if consumed > len(buffer) {
+			consumed = len(buffer)
+		}
+		buffer = buffer[consumed:]
+	}
+	this.pending = append([]byte(nil), buffer...)
+}
@@ -0,0 +1,36 @@
+package loggedio
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestOnReadBytes(t *testing.T) {
+	source := bytes.NewBufferString("first\nsec")
+	logged := StringToLog(source, "", "", "", "")
+
+	var frames []string
+	logged.OnReadBytes(func(b []byte) int {
+		end := bytes.IndexByte(b, '\n')
+		if end < 0 {
+			return 0
+		}
+		frames = append(frames, string(b[:end]))
+		return end + 1
+	})
+
+	buffer := make([]byte, 4)
+	for i := 0; i < 3; i++ {
+		logged.Read(buffer)
+	}
+	expectNumber(t, 1, len(frames))
+
+	source.WriteString("ond\nthird\n")
+	for i := 0; i < 3; i++ {
+		logged.Read(buffer)
+	}
+	expectNumber(t, 3, len(frames))
+	if len(frames) == 3 && (frames[0] != "first" || frames[1] != "second" || frames[2] != "third") {
+		t.Errorf("Unexpected frames %v", frames)
+	}
+}
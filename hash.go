@@ -0,0 +1,29 @@
+package loggedio
+
+import (
+	"crypto"
+	_ "crypto/sha256"
+	_ "crypto/sha512"
+	"encoding/hex"
+	"fmt"
+	"strings"
+)
+
+// hashName returns the lowercase name of a hash algorithm without
+// punctuation, for example "sha256".
+func hashName(hash crypto.Hash) string {
+	return strings.ToLower(strings.Replace(hash.String(), "-", "", -1))
+}
+
+// hashPayload returns the digest of b as lowercase hex.
+func hashPayload(hash crypto.Hash, b []byte) string {
+	hasher := hash.New()
+	hasher.Write(b)
+	return hex.EncodeToString(hasher.Sum(nil))
+}
+
+// describeHashedPayload renders the length and digest of b in place of its
+// contents, for example "len=4 sha256=9f86d081...".
+func describeHashedPayload(hash crypto.Hash, b []byte) string {
+	return fmt.Sprintf("len=%v %v=%v", len(b), hashName(hash), hashPayload(hash, b))
+}
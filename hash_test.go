@@ -0,0 +1,29 @@
+package loggedio
+
+import (
+	"bytes"
+	"crypto"
+	"testing"
+)
+
+const testSHA256 = "9f86d081884c7d659a2feaa0c55ad015a3bf4f1b2b0b822cd15d6c15b0f00a08"
+
+func TestHashPayloads(t *testing.T) {
+	buffer := &bytes.Buffer{}
+	logged := StringToWriter(&MockIO{}, buffer, "R [%v]", "W %v", "E [%v: %v]", "C")
+	logged.HashPayloads = crypto.SHA256
+	logged.Write([]byte("test"))
+	expectBufferContents(t, buffer, "W len=4 sha256="+testSHA256)
+}
+
+func TestHashPayloadsJSON(t *testing.T) {
+	buffer := &bytes.Buffer{}
+	logged := JSONToWriter(&MockIO{}, buffer)
+	logged.HashPayloads = crypto.SHA256
+	logged.Write([]byte("test"))
+	events := decodeJSONEvents(t, buffer)
+	expectNumber(t, 1, len(events))
+	expectNumber(t, 0, len(events[0].Data))
+	expectNumber(t, 4, events[0].Length)
+	expectString(t, "sha256:"+testSHA256, events[0].Hash)
+}
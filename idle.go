@@ -0,0 +1,32 @@
+package loggedio
+
+import (
+	"fmt"
+)
+
+// checkIdle reports an idle marker if there's been no traffic for at least
+// IdleMarkerInterval.
+func (this *LoggedIOProxy) checkIdle() {
+	this.mutex.Lock()
+	idleFor := this.clock.Now().Sub(this.lastActivityAt)
+	this.mutex.Unlock()
+	if idleFor >= this.IdleMarkerInterval {
+		this.reportNotify(fmt.Sprintf("LoggedIO: idle (no traffic for %v)\n", idleFor))
+	}
+}
+
+// checkReadGap reports a stall if the time since the previous read that
+// returned data exceeds ReadGapThreshold.
+func (this *LoggedIOProxy) checkReadGap() {
+	if this.ReadGapThreshold <= 0 {
+		return
+	}
+	now := this.clock.Now()
+	this.mutex.Lock()
+	lastReadAt := this.lastReadAt
+	this.lastReadAt = now
+	this.mutex.Unlock()
+	if gap := now.Sub(lastReadAt); !lastReadAt.IsZero() && gap > this.ReadGapThreshold {
+		this.reportNotify(fmt.Sprintf("LoggedIO: Read gap=%v (stall?)\n", gap))
+	}
+}
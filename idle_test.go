@@ -0,0 +1,67 @@
+package loggedio
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+)
+
+func waitForSuffix(t *testing.T, buffer *SyncBuffer, suffix string) {
+	deadline := time.Now().Add(5 * time.Second)
+	for !strings.HasSuffix(buffer.String(), suffix) {
+		if time.Now().After(deadline) {
+			t.Fatalf("Timed out waiting for %q; got %q", suffix, buffer.String())
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestIdleMarker(t *testing.T) {
+	buffer := &SyncBuffer{}
+	logged := StringToWriter(&MockIO{}, buffer, "R [%v]\n", "W [%v]\n", "E [%v: %v]\n", "C\n")
+	clock := newMockClock()
+	logged.SetClock(clock)
+	logged.IdleMarkerInterval = 10 * time.Second
+
+	logged.Write([]byte("abc"))
+	clock.Advance(5 * time.Second)
+	clock.Advance(10 * time.Second)
+	waitForSuffix(t, buffer, "LoggedIO: idle (no traffic for 15s)\n")
+	expectString(t, "W [abc]\nLoggedIO: idle (no traffic for 15s)\n", buffer.String())
+
+	logged.Close()
+	waitForSuffix(t, buffer, "C\n")
+}
+
+func TestIdleMarkerDisabled(t *testing.T) {
+	logged := StringToWriter(&MockIO{}, &SyncBuffer{}, "", "", "", "")
+	clock := newMockClock()
+	logged.SetClock(clock)
+	logged.Write([]byte("abc"))
+	if clock.ticker != nil {
+		t.Errorf("Expected no ticker to be created")
+	}
+}
+
+func TestReadGapThreshold(t *testing.T) {
+	buffer := &bytes.Buffer{}
+	clock := newMockClock()
+	logged := StringToWriter(&MockIO{}, buffer, "R [%v]\n", "W [%v]\n", "E [%v: %v]\n", "C\n")
+	logged.SetClock(clock)
+	logged.ReadGapThreshold = 2 * time.Second
+
+	logged.Read(make([]byte, 1))
+	clock.Advance(2 * time.Second)
+	logged.Read(make([]byte, 1))
+	clock.Advance(5200 * time.Millisecond)
+	logged.Write([]byte("x"))
+	logged.Read(nil)
+	logged.Read(make([]byte, 1))
+	expectBufferContents(t, buffer, ""+
+		"R [a]\n"+
+		"R [a]\n"+
+		"W [x]\n"+
+		"LoggedIO: Read gap=5.2s (stall?)\n"+
+		"R [a]\n")
+}
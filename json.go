@@ -0,0 +1,101 @@
+package loggedio
+
+import (
+	"encoding/json"
+	"io"
+)
+
+// DirectionLabels holds the text used to label the read and write directions
+// in direction-tagged output such as JSON events.
+type DirectionLabels struct {
+	Read  string
+	Write string
+}
+
+// The direction labels used unless otherwise configured.
+var DefaultDirectionLabels = DirectionLabels{
+	Read:  "read",
+	Write: "write",
+}
+
+type jsonEvent struct {
+	Label      string `json:"label,omitempty"`
+	Phase      string `json:"phase,omitempty"`
+	Event      string `json:"event"`
+	Sequence   uint64 `json:"seq"`
+	Completion uint64 `json:"completion,omitempty"`
+	Data       []byte `json:"data,omitempty"`
+	Length     int    `json:"len,omitempty"`
+	Hash       string `json:"hash,omitempty"`
+	SinceWrite string `json:"since_write,omitempty"`
+	Location   string `json:"location,omitempty"`
+	Error      string `json:"error,omitempty"`
+	Message    string `json:"message,omitempty"`
+}
+
+// JSONToWriter creates a logged I/O proxy that writes each event as a single
+// line of JSON to the specified writer. The "event" field holds the event type
+// ("read", "write", "error", "close", or "notify"), and the "seq" field holds
+// the event's sequence number. Read and write payloads are stored base64
+// encoded in the "data" field, and errors store their location and message in
+// the "location" and "error" fields. Notifications (such as session summaries)
+// store their text in the "message" field. If the proxy's
+// ReportWriteToReadLatency option is set, the first read after a write stores
+// the elapsed time in the "since_write" field. If the proxy's HashPayloads
+// option is set, payloads are replaced by their length in the "len" field and
+// their digest in the "hash" field (for example "sha256:9f86d081..."). If the
+// proxy's TrackCompletionOrder option is set, reads and writes store their
+// completion order in the "completion" field. If the proxy's Label option is
+// set, every event stores it in the "label" field. If the proxy's
+// HandshakeBytes or HandshakeDuration option is set, every event stores its
+// connection phase in the "phase" field. If the proxy's CombineDataAndError
+// option is set, an error returned along with read or written data is stored
+// in that event's "location" and "error" fields instead of as a separate
+// event.
+//
+// The read and write event names can be changed via the proxy's
+// DirectionLabels field.
+func JSONToWriter(proxiedObject interface{}, writer io.Writer) *LoggedIOProxy {
+	target := newReportTarget(writer)
+	encoder := json.NewEncoder(target)
+	var this *LoggedIOProxy
+	this = newProxy(proxiedObject, func(event *Event) {
+		if event.ReportedWithData {
+			return
+		}
+		encoded := jsonEvent{
+			Label:      this.Label,
+			Sequence:   event.Sequence,
+			Completion: event.Completion,
+			Data:       event.Data,
+			Location:   event.Location,
+			Message:    event.Message,
+		}
+		switch event.Type {
+		case EventRead:
+			encoded.Event = this.DirectionLabels.Read
+		case EventWrite:
+			encoded.Event = this.DirectionLabels.Write
+		default:
+			encoded.Event = event.Type.String()
+		}
+		if this.HashPayloads != 0 && event.Data != nil {
+			encoded.Data = nil
+			encoded.Length = len(event.Data)
+			encoded.Hash = hashName(this.HashPayloads) + ":" +
+				hashPayload(this.HashPayloads, event.Data)
+		}
+		if event.FollowsWrite {
+			encoded.SinceWrite = event.SinceWrite.String()
+		}
+		if event.Err != nil {
+			encoded.Error = event.Err.Error()
+		}
+		if event.Phase != PhaseNone {
+			encoded.Phase = event.Phase.String()
+		}
+		encoder.Encode(encoded)
+	})
+	this.target = target
+	return this
+}
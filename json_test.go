@@ -0,0 +1,86 @@
+package loggedio
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+)
+
+func decodeJSONEvents(t *testing.T, buffer *bytes.Buffer) (events []jsonEvent) {
+	decoder := json.NewDecoder(buffer)
+	for decoder.More() {
+		var event jsonEvent
+		if err := decoder.Decode(&event); err != nil {
+			t.Fatal(err)
+		}
+		events = append(events, event)
+	}
+	return
+}
+
+func TestJSON(t *testing.T) {
+	proxied := &MockIO{FailAfterReadByteCount: 3}
+	buffer := &bytes.Buffer{}
+	logged := JSONToWriter(proxied, buffer)
+
+	logged.Read(make([]byte, 3))
+	logged.Write([]byte("test"))
+	logged.Close()
+
+	events := decodeJSONEvents(t, buffer)
+	expectNumber(t, 4, len(events))
+	if events[0].Event != "read" || string(events[0].Data) != "abc" {
+		t.Errorf("Unexpected read event %v", events[0])
+	}
+	if events[1].Event != "error" || events[1].Location != "Read()" || events[1].Error != "ERROR!" {
+		t.Errorf("Unexpected error event %v", events[1])
+	}
+	if events[2].Event != "write" || string(events[2].Data) != "test" {
+		t.Errorf("Unexpected write event %v", events[2])
+	}
+	if events[3].Event != "close" {
+		t.Errorf("Unexpected close event %v", events[3])
+	}
+}
+
+func TestJSONDirectionLabels(t *testing.T) {
+	proxied := &MockIO{}
+	buffer := &bytes.Buffer{}
+	logged := JSONToWriter(proxied, buffer)
+	logged.DirectionLabels = DirectionLabels{Read: "recv", Write: "send"}
+
+	logged.Read(make([]byte, 3))
+	logged.Write([]byte("test"))
+
+	events := decodeJSONEvents(t, buffer)
+	expectNumber(t, 2, len(events))
+	if events[0].Event != "recv" {
+		t.Errorf("Expected event \"recv\" but got \"%v\"", events[0].Event)
+	}
+	if events[1].Event != "send" {
+		t.Errorf("Expected event \"send\" but got \"%v\"", events[1].Event)
+	}
+}
+
+func TestJSONLabel(t *testing.T) {
+	buffer := &bytes.Buffer{}
+	logged := JSONToWriter(&MockIO{}, buffer)
+	logged.Label = "conn-1"
+	logged.Write([]byte("a"))
+	events := decodeJSONEvents(t, buffer)
+	expectNumber(t, 1, len(events))
+	expectString(t, "conn-1", events[0].Label)
+}
+
+func TestJSONCombineDataAndError(t *testing.T) {
+	buffer := &bytes.Buffer{}
+	logged := JSONToWriter(&MockIO{FailAfterReadByteCount: 3}, buffer)
+	logged.CombineDataAndError = true
+	logged.Read(make([]byte, 10))
+	events := decodeJSONEvents(t, buffer)
+	expectNumber(t, 1, len(events))
+	expectString(t, "read", events[0].Event)
+	expectString(t, "abc", string(events[0].Data))
+	expectString(t, "Read()", events[0].Location)
+	expectString(t, "ERROR!", events[0].Error)
+}
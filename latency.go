@@ -0,0 +1,72 @@
+package loggedio
+
+import (
+	"math"
+	"sort"
+	"sync"
+	"time"
+)
+
+// The upper bound of a histogram's final bucket, which holds everything
+// above the highest configured boundary.
+const MaxLatency = time.Duration(math.MaxInt64)
+
+// BucketCount is a single histogram bucket, counting the operations that took
+// longer than the previous bucket's UpperBound, and at most UpperBound.
+type BucketCount struct {
+	UpperBound time.Duration
+	Count      uint64
+}
+
+type latencyHistograms struct {
+	mutex      sync.Mutex
+	boundaries []time.Duration
+	// Indexed by Direction, then by bucket.
+	counts [2][]uint64
+}
+
+// TrackLatency starts timing each Read() and Write() call made through the
+// proxy, accumulating the durations into histogram buckets with the given
+// upper boundaries (plus a final bucket for anything longer). The durations
+// are measured using the proxy's clock. Latency isn't tracked by default, and
+// costs nothing when off.
+//
+// This must be called before the proxy is first used.
+func (this *LoggedIOProxy) TrackLatency(boundaries ...time.Duration) {
+	sorted := append([]time.Duration(nil), boundaries...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+	histograms := &latencyHistograms{boundaries: append(sorted, MaxLatency)}
+	for i := range histograms.counts {
+		histograms.counts[i] = make([]uint64, len(histograms.boundaries))
+	}
+	this.latency = histograms
+}
+
+// LatencyHistogram returns a snapshot of the latency histogram for the
+// specified direction, or nil if latency isn't being tracked or the direction
+// is invalid. The last bucket's UpperBound is MaxLatency.
+func (this *LoggedIOProxy) LatencyHistogram(direction Direction) []BucketCount {
+	histograms := this.latency
+	if histograms == nil || !direction.isValid() {
+		return nil
+	}
+	histograms.mutex.Lock()
+	defer histograms.mutex.Unlock()
+	buckets := make([]BucketCount, len(histograms.boundaries))
+	for i, boundary := range histograms.boundaries {
+		buckets[i] = BucketCount{
+			UpperBound: boundary,
+			Count:      histograms.counts[direction][i],
+		}
+	}
+	return buckets
+}
+
+func (this *latencyHistograms) record(direction Direction, latency time.Duration) {
+	index := sort.Search(len(this.boundaries), func(i int) bool {
+		return latency <= this.boundaries[i]
+	})
+	this.mutex.Lock()
+	this.counts[direction][index]++
+	this.mutex.Unlock()
+}
@@ -0,0 +1,55 @@
+package loggedio
+
+import (
+	"testing"
+	"time"
+)
+
+// MockSlowWriter advances its clock by the next of its delays on each write.
+type MockSlowWriter struct {
+	clock  *MockClock
+	delays []time.Duration
+}
+
+func (this *MockSlowWriter) Write(b []byte) (n int, err error) {
+	this.clock.Advance(this.delays[0])
+	this.delays = this.delays[1:]
+	return len(b), nil
+}
+
+func TestLatencyHistogram(t *testing.T) {
+	clock := newMockClock()
+	writer := &MockSlowWriter{clock: clock, delays: []time.Duration{
+		0, 50 * time.Millisecond, 51 * time.Millisecond, 150 * time.Millisecond, 0,
+	}}
+	logged, _ := NewWithMemorySink(writer)
+	logged.SetClock(clock)
+	logged.TrackLatency(100*time.Millisecond, 50*time.Millisecond)
+	for range writer.delays {
+		logged.Write([]byte("a"))
+	}
+
+	buckets := logged.LatencyHistogram(DirectionWrite)
+	expectNumber(t, 3, len(buckets))
+	expectNumber(t, int(50*time.Millisecond), int(buckets[0].UpperBound))
+	expectNumber(t, int(100*time.Millisecond), int(buckets[1].UpperBound))
+	expectNumber(t, int(MaxLatency), int(buckets[2].UpperBound))
+	expectNumber(t, 3, int(buckets[0].Count))
+	expectNumber(t, 1, int(buckets[1].Count))
+	expectNumber(t, 1, int(buckets[2].Count))
+
+	for _, bucket := range logged.LatencyHistogram(DirectionRead) {
+		expectNumber(t, 0, int(bucket.Count))
+	}
+	if logged.LatencyHistogram(Direction(7)) != nil {
+		t.Errorf("Expected no histogram for an invalid direction")
+	}
+}
+
+func TestLatencyOffByDefault(t *testing.T) {
+	logged, _ := NewWithMemorySink(&MockIO{})
+	logged.Write([]byte("a"))
+	if logged.LatencyHistogram(DirectionWrite) != nil {
+		t.Errorf("Expected no histogram")
+	}
+}
@@ -0,0 +1,84 @@
+package loggedio
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+)
+
+const lengthPrefixedHeaderLength = 5
+
+// LengthPrefixedToWriter creates a logged I/O proxy that writes each event to
+// writer as a binary frame, for piping events to another process. Unlike text
+// output, frames are binary safe and need no delimiters. Each frame consists
+// of:
+//
+//   - The payload length, as a 4 byte big endian integer.
+//   - The event type (EventRead, EventWrite, etc), as one byte.
+//   - The payload: the data of reads and writes, the message of notifications,
+//     nothing for closes, and for errors the location and error message
+//     separated by a zero byte.
+//
+// Frames can be decoded with DecodeFrame().
+func LengthPrefixedToWriter(proxiedObject interface{}, writer io.Writer) *LoggedIOProxy {
+	target := newReportTarget(writer)
+	this := newProxy(proxiedObject, func(event *Event) {
+		var payload []byte
+		switch event.Type {
+		case EventRead, EventWrite:
+			payload = event.Data
+		case EventError:
+			payload = []byte(event.Location + "\x00" + event.Err.Error())
+		case EventNotify:
+			payload = []byte(event.Message)
+		}
+		frame := make([]byte, lengthPrefixedHeaderLength, lengthPrefixedHeaderLength+len(payload))
+		binary.BigEndian.PutUint32(frame, uint32(len(payload)))
+		frame[4] = byte(event.Type)
+		target.Write(append(frame, payload...))
+	})
+	this.target = target
+	return this
+}
+
+// DecodeFrame reads the next frame written by LengthPrefixedToWriter from r,
+// and returns it as an event with its Type and one of Data (reads and
+// writes), Location and Err (errors), or Message (notifications) set. Errors
+// are decoded as plain errors carrying the original message.
+//
+// io.EOF is returned if r ends cleanly between frames, and
+// io.ErrUnexpectedEOF if it ends partway through one.
+func DecodeFrame(r io.Reader) (*Event, error) {
+	header := make([]byte, lengthPrefixedHeaderLength)
+	if _, err := io.ReadFull(r, header); err != nil {
+		return nil, err
+	}
+	payload := make([]byte, binary.BigEndian.Uint32(header))
+	if _, err := io.ReadFull(r, payload); err != nil {
+		if err == io.EOF {
+			err = io.ErrUnexpectedEOF
+		}
+		return nil, err
+	}
+
+	event := &Event{Type: EventType(header[4])}
+	switch event.Type {
+	case EventRead, EventWrite:
+		event.Data = payload
+	case EventError:
+		parts := strings.SplitN(string(payload), "\x00", 2)
+		if len(parts) != 2 {
+			return nil, fmt.Errorf("LoggedIO: Malformed error frame %q", payload)
+		}
+		event.Location = parts[0]
+		event.Err = errors.New(parts[1])
+	case EventClose:
+	case EventNotify:
+		event.Message = string(payload)
+	default:
+		return nil, fmt.Errorf("LoggedIO: Unknown event type %v in frame", header[4])
+	}
+	return event, nil
+}
@@ -0,0 +1,47 @@
+package loggedio
+
+import (
+	"bytes"
+	"io"
+	"testing"
+)
+
+func TestLengthPrefixedRoundTrip(t *testing.T) {
+	buffer := &bytes.Buffer{}
+	logged := LengthPrefixedToWriter(&MockIO{FailAfterReadByteCount: 3}, buffer)
+	logged.Read(make([]byte, 5))
+	logged.Close()
+
+	expectString(t, "\x00\x00\x00\x03\x00abc", buffer.String()[:8])
+
+	event, err := DecodeFrame(buffer)
+	expectNoError(t, err)
+	expectNumber(t, int(EventRead), int(event.Type))
+	expectString(t, "abc", string(event.Data))
+
+	event, err = DecodeFrame(buffer)
+	expectNoError(t, err)
+	expectNumber(t, int(EventError), int(event.Type))
+	expectString(t, "Read()", event.Location)
+	expectString(t, "ERROR!", event.Err.Error())
+
+	event, err = DecodeFrame(buffer)
+	expectNoError(t, err)
+	expectNumber(t, int(EventClose), int(event.Type))
+
+	_, err = DecodeFrame(buffer)
+	if err != io.EOF {
+		t.Errorf("Expected io.EOF but got %v", err)
+	}
+}
+
+func TestDecodeFrameTruncated(t *testing.T) {
+	_, err := DecodeFrame(bytes.NewReader([]byte{0, 0, 0, 5, 0, 'a'}))
+	if err != io.ErrUnexpectedEOF {
+		t.Errorf("Expected io.ErrUnexpectedEOF but got %v", err)
+	}
+	_, err = DecodeFrame(bytes.NewReader([]byte{0, 0}))
+	if err != io.ErrUnexpectedEOF {
+		t.Errorf("Expected io.ErrUnexpectedEOF but got %v", err)
+	}
+}
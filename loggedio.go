@@ -3,22 +3,27 @@
 //
 // LoggedIO uses duck typing, meaning that the proxied object is not checked
 // for compatibility until you actually call a method. If you attempt to call
-// a proxied method that the object doesn't actually implement, it will panic.
-// It's recommended to cast to the expected interface before use for better type
-// safety.
+// a proxied method that the object doesn't actually implement, it will panic
+// (unless the proxy's RecoverMethodMismatch option is set). It's recommended
+// to cast to the expected interface before use for better type safety.
 //
 // Loggedio supports reporting to files, writers and the go log out of the box.
 // Other reporting mechanisms can easily be added using `loggedio.Generic()`.
 package loggedio
 
 import (
+	"crypto"
+	"encoding/hex"
 	"fmt"
 	"io"
 	"io/ioutil"
 	"log"
 	"net"
 	"os"
+	"reflect"
 	"strings"
+	"sync"
+	"sync/atomic"
 	"time"
 )
 
@@ -28,13 +33,18 @@ func Generic(proxiedObject interface{},
 	reportReadEvent, reportWriteEvent func(b []byte),
 	reportErrorEvent func(location string, err error),
 	reportCloseEvent func()) *LoggedIOProxy {
-	this := new(LoggedIOProxy)
-	this.proxiedObject = proxiedObject
-	this.reportReadEvent = reportReadEvent
-	this.reportWriteEvent = reportWriteEvent
-	this.reportErrorEvent = reportErrorEvent
-	this.reportCloseEvent = reportCloseEvent
-	return this
+	return newProxy(proxiedObject, func(event *Event) {
+		switch event.Type {
+		case EventRead:
+			reportReadEvent(event.Data)
+		case EventWrite:
+			reportWriteEvent(event.Data)
+		case EventError:
+			reportErrorEvent(event.Location, event.Err)
+		case EventClose:
+			reportCloseEvent()
+		}
+	})
 }
 
 // StringToLog creates a logged I/O proxy that writes the contents of the data
@@ -46,12 +56,8 @@ func Generic(proxiedObject interface{},
 // be disabled.
 func StringToLog(proxiedObject interface{},
 	readFmt, writeFmt, errorFmt, closeMsg string) *LoggedIOProxy {
-
-	return Generic(proxiedObject,
-		byteFunc(readFmt, func(b []byte) { log.Printf(readFmt, string(b)) }),
-		byteFunc(writeFmt, func(b []byte) { log.Printf(writeFmt, string(b)) }),
-		errFunc(errorFmt, func(location string, err error) { log.Printf(errorFmt, location, err) }),
-		closeFunc(closeMsg, func() { log.Printf("%v", closeMsg) }))
+	return newTextProxy(proxiedObject, log.Printf, nil, EncodingString,
+		readFmt, writeFmt, errorFmt, closeMsg)
 }
 
 // HexToLog creates a logged I/O proxy that writes the hex encoded contents of
@@ -63,11 +69,8 @@ func StringToLog(proxiedObject interface{},
 // be disabled.
 func HexToLog(proxiedObject interface{},
 	readFmt, writeFmt, errorFmt, closeMsg string) *LoggedIOProxy {
-	return Generic(proxiedObject,
-		byteFunc(readFmt, func(b []byte) { log.Printf(readFmt, toHex(b)) }),
-		byteFunc(readFmt, func(b []byte) { log.Printf(writeFmt, toHex(b)) }),
-		errFunc(errorFmt, func(location string, err error) { log.Printf(errorFmt, location, err) }),
-		closeFunc(closeMsg, func() { log.Printf("%v", closeMsg) }))
+	return newTextProxy(proxiedObject, log.Printf, nil, EncodingHex,
+		readFmt, writeFmt, errorFmt, closeMsg)
 }
 
 // StringToWriter creates a logged I/O proxy that writes the contents of the
@@ -79,11 +82,8 @@ func HexToLog(proxiedObject interface{},
 // be disabled.
 func StringToWriter(proxiedObject interface{}, writer io.Writer,
 	readFmt, writeFmt, errorFmt, closeMsg string) *LoggedIOProxy {
-	return Generic(proxiedObject,
-		byteFunc(readFmt, func(b []byte) { fmt.Fprintf(writer, readFmt, string(b)) }),
-		byteFunc(readFmt, func(b []byte) { fmt.Fprintf(writer, writeFmt, string(b)) }),
-		errFunc(errorFmt, func(location string, err error) { fmt.Fprintf(writer, errorFmt, location, err) }),
-		closeFunc(closeMsg, func() { writer.Write([]byte(closeMsg)) }))
+	return newTextProxy(proxiedObject, nil, writer, EncodingString,
+		readFmt, writeFmt, errorFmt, closeMsg)
 }
 
 // HexToWriter creates a logged I/O proxy that writes the hex encoded contents
@@ -95,11 +95,83 @@ func StringToWriter(proxiedObject interface{}, writer io.Writer,
 // be disabled.
 func HexToWriter(proxiedObject interface{}, writer io.Writer,
 	readFmt, writeFmt, errorFmt, closeMsg string) *LoggedIOProxy {
-	return Generic(proxiedObject,
-		byteFunc(readFmt, func(b []byte) { fmt.Fprintf(writer, readFmt, toHex(b)) }),
-		byteFunc(readFmt, func(b []byte) { fmt.Fprintf(writer, writeFmt, toHex(b)) }),
-		errFunc(errorFmt, func(location string, err error) { fmt.Fprintf(writer, errorFmt, location, err) }),
-		closeFunc(closeMsg, func() { writer.Write([]byte(closeMsg)) }))
+	return newTextProxy(proxiedObject, nil, writer, EncodingHex,
+		readFmt, writeFmt, errorFmt, closeMsg)
+}
+
+// StringToLoggers creates a logged I/O proxy that writes the contents of the
+// data as strings to separate loggers: reads to readLog, writes to writeLog,
+// and all other events (errors, closes, and notifications) to otherLog. The
+// same logger can be passed more than once. readFmt and writeFmt must contain
+// a single %v for the payload contents. errFmt must contain a %v for the
+// location where the error occured, and a second %v for the error payload, in
+// that order.
+//
+// If any string param is empty, that particular reporting functionality will
+// be disabled.
+func StringToLoggers(proxiedObject interface{}, readLog, writeLog, otherLog *log.Logger,
+	readFmt, writeFmt, errorFmt, closeMsg string) *LoggedIOProxy {
+	return newLoggersProxy(proxiedObject, readLog, writeLog, otherLog, EncodingString,
+		readFmt, writeFmt, errorFmt, closeMsg)
+}
+
+// HexToLoggers creates a logged I/O proxy that writes the hex encoded contents
+// of the data to separate loggers: reads to readLog, writes to writeLog, and
+// all other events (errors, closes, and notifications) to otherLog. The same
+// logger can be passed more than once. readFmt and writeFmt must contain a
+// single %v for the payload contents. errFmt must contain a %v for the
+// location where the error occured, and a second %v for the error payload, in
+// that order.
+//
+// If any string param is empty, that particular reporting functionality will
+// be disabled.
+func HexToLoggers(proxiedObject interface{}, readLog, writeLog, otherLog *log.Logger,
+	readFmt, writeFmt, errorFmt, closeMsg string) *LoggedIOProxy {
+	return newLoggersProxy(proxiedObject, readLog, writeLog, otherLog, EncodingHex,
+		readFmt, writeFmt, errorFmt, closeMsg)
+}
+
+func newLoggersProxy(proxiedObject interface{}, readLog, writeLog, otherLog *log.Logger,
+	encoding Encoding, readFmt, writeFmt, errorFmt, closeMsg string) *LoggedIOProxy {
+	formatter := newTextFormatter(proxiedObject, otherLog.Printf, nil, encoding,
+		readFmt, writeFmt, errorFmt, closeMsg)
+	formatter.readPrintf = readLog.Printf
+	formatter.writePrintf = writeLog.Printf
+	formatter.targetDescription = "loggers"
+	return formatter.proxy
+}
+
+// GoLiteralToWriter creates a logged I/O proxy that writes the contents of the
+// data as double quoted Go/C string literals to the specified writer, ready to
+// be pasted into test code. readFmt and writeFmt must contain a single %v for
+// the payload contents. errFmt must contain a %v for the location where the
+// error occured, and a second %v for the error payload, in that order.
+//
+// If any string param is empty, that particular reporting functionality will
+// be disabled.
+func GoLiteralToWriter(proxiedObject interface{}, writer io.Writer,
+	readFmt, writeFmt, errorFmt, closeMsg string) *LoggedIOProxy {
+	return newTextProxy(proxiedObject, nil, writer, EncodingGoLiteral,
+		readFmt, writeFmt, errorFmt, closeMsg)
+}
+
+// MixedToWriter creates a logged I/O proxy that writes the contents of the
+// data to the specified writer, rendering reads with readEncoding and writes
+// with writeEncoding (for example reads as hex and writes as strings).
+// readFmt and writeFmt must contain a single %v for the payload contents.
+// errFmt must contain a %v for the location where the error occured, and a
+// second %v for the error payload, in that order.
+//
+// If any string param is empty, that particular reporting functionality will
+// be disabled.
+func MixedToWriter(proxiedObject interface{}, writer io.Writer, readEncoding, writeEncoding Encoding,
+	readFmt, writeFmt, errorFmt, closeMsg string) *LoggedIOProxy {
+	formatter := newTextFormatter(proxiedObject, nil, writer, readEncoding,
+		readFmt, writeFmt, errorFmt, closeMsg)
+	formatter.mixedEncodings = true
+	formatter.readEncoding = readEncoding
+	formatter.writeEncoding = writeEncoding
+	return formatter.proxy
 }
 
 // DumpToWriter creates a logged I/O proxy that dumps the contents of the data
@@ -109,24 +181,94 @@ func HexToWriter(proxiedObject interface{}, writer io.Writer,
 //
 // If any string param is empty, that particular reporting functionality will
 // be disabled.
+//
+// If the proxy's PreviewBytes option is set, a short hex preview of each read
+// and write is also written to the notify writer. If its DumpTransform option
+// is set, payloads are transformed before being dumped.
+//
+// If the same writer is passed more than once, or the proxy's SerializeOutput
+// option is set, events are written one at a time, so that concurrent reads
+// and writes can't interleave their output.
 func DumpToWriters(proxiedObject interface{}, readWriter, writeWriter, notifyWriter io.Writer,
 	errorFmt, closeMsg string) *LoggedIOProxy {
 	errorFunc := errFunc(errorFmt, func(location string, err error) {
 		fmt.Fprintf(notifyWriter, errorFmt, location, err)
 	})
-	return Generic(proxiedObject,
+	isSharedWriter := isSameWriter(readWriter, writeWriter) ||
+		isSameWriter(readWriter, notifyWriter) ||
+		isSameWriter(writeWriter, notifyWriter)
+	var mutex sync.Mutex
+	var this *LoggedIOProxy
+	serialized := func(function func()) {
+		if isSharedWriter || this.SerializeOutput {
+			mutex.Lock()
+			defer mutex.Unlock()
+		}
+		function()
+	}
+	this = Generic(proxiedObject,
 		func(b []byte) {
-			if _, err := readWriter.Write(b); err != nil {
-				errorFunc("LoggedIO readWriter", err)
-			}
+			b = this.transformDump(b)
+			serialized(func() {
+				if _, err := readWriter.Write(b); err != nil {
+					errorFunc("LoggedIO readWriter", err)
+				}
+				this.writePreview(notifyWriter, this.DirectionLabels.Read, b)
+			})
 		},
 		func(b []byte) {
-			if _, err := writeWriter.Write(b); err != nil {
-				errorFunc("LoggedIO writeWriter", err)
-			}
+			b = this.transformDump(b)
+			serialized(func() {
+				if _, err := writeWriter.Write(b); err != nil {
+					errorFunc("LoggedIO writeWriter", err)
+				}
+				this.writePreview(notifyWriter, this.DirectionLabels.Write, b)
+			})
 		},
-		errFunc(errorFmt, func(location string, err error) { fmt.Fprintf(notifyWriter, errorFmt, location, err) }),
-		closeFunc(closeMsg, func() { notifyWriter.Write([]byte(closeMsg)) }))
+		errFunc(errorFmt, func(location string, err error) {
+			serialized(func() { fmt.Fprintf(notifyWriter, errorFmt, location, err) })
+		}),
+		closeFunc(closeMsg, func() {
+			serialized(func() { notifyWriter.Write([]byte(closeMsg)) })
+		}))
+	notify := writerNotifyFunc(notifyWriter)
+	this.SetNotifyCallback(func(message string) {
+		serialized(func() { notify(message) })
+	})
+	return this
+}
+
+// isSameWriter returns true if a and b are the same writer. Writers that can't
+// be compared are considered different.
+func isSameWriter(a, b io.Writer) bool {
+	if a == nil || b == nil || reflect.TypeOf(a) != reflect.TypeOf(b) ||
+		!reflect.TypeOf(a).Comparable() {
+		return false
+	}
+	return a == b
+}
+
+// transformDump applies DumpTransform (if set) to a copy of b.
+func (this *LoggedIOProxy) transformDump(b []byte) []byte {
+	if this.DumpTransform == nil {
+		return b
+	}
+	return this.DumpTransform(append([]byte(nil), b...))
+}
+
+// writePreview writes the first PreviewBytes bytes of b to writer as hex, if
+// PreviewBytes is set.
+func (this *LoggedIOProxy) writePreview(writer io.Writer, direction string, b []byte) {
+	if this.PreviewBytes <= 0 {
+		return
+	}
+	preview := b
+	ellipsis := ""
+	if len(preview) > this.PreviewBytes {
+		preview = preview[:this.PreviewBytes]
+		ellipsis = "…"
+	}
+	fmt.Fprintf(writer, "%v %v bytes: %v%v\n", direction, len(b), toHex(preview), ellipsis)
 }
 
 // DumpToFiles creates a logged I/O proxy that dumps the contents of the data
@@ -141,9 +283,26 @@ func DumpToWriters(proxiedObject interface{}, readWriter, writeWriter, notifyWri
 // be disabled.
 func DumpToFiles(proxiedObject interface{}, readFilename, writeFilename, notifyFilename string,
 	errorFmt, closeMsg string) *LoggedIOProxy {
-	return DumpToWriters(proxiedObject, writerForFile(readFilename),
-		writerForFile(writeFilename), writerForFile(notifyFilename),
-		errorFmt, closeMsg)
+	readWriter := writerForFile(readFilename)
+	writeWriter := writerForFile(writeFilename)
+	notifyWriter := writerForFile(notifyFilename)
+	this := DumpToWriters(proxiedObject, readWriter, writeWriter, notifyWriter, errorFmt, closeMsg)
+	this.ownWriters(readWriter, writeWriter, notifyWriter)
+	return this
+}
+
+// DumpToFilesLazily is like DumpToFiles, except that each file is only
+// created (and truncated) when the first data is written to it, so a
+// direction that never sees any data leaves no empty file behind. The special
+// file names "stdout", "stderr" and "null" behave as they do in DumpToFiles.
+func DumpToFilesLazily(proxiedObject interface{}, readFilename, writeFilename, notifyFilename string,
+	errorFmt, closeMsg string) *LoggedIOProxy {
+	readWriter := lazyWriterForFile(readFilename)
+	writeWriter := lazyWriterForFile(writeFilename)
+	notifyWriter := lazyWriterForFile(notifyFilename)
+	this := DumpToWriters(proxiedObject, readWriter, writeWriter, notifyWriter, errorFmt, closeMsg)
+	this.ownWriters(readWriter, writeWriter, notifyWriter)
+	return this
 }
 
 // LoggedIOProxy implements io.Reader, io.Writer, io.Closer, and net.Conn,
@@ -151,86 +310,986 @@ func DumpToFiles(proxiedObject interface{}, readFilename, writeFilename, notifyF
 // Callbacks are called AFTER the event occurs. If an error occurs on a read or
 // write, only the bytes actually read/written will be reported (if > 0), after
 // which the error will be reported.
+//
+// Timeout errors (net.Error with Timeout() == true) are reported with
+// " timeout" appended to their location (for example "Read() timeout"), so
+// that they can be told apart from hard failures.
+//
+// If Close() is called while a Read() or Write() is in flight, the resulting
+// error from the unblocked operation is reported with the location
+// "Read() after close" or "Write() after close" so that it can be told apart
+// from a genuine I/O failure.
+//
+// The exported fields are options that modify the reporting behavior. They
+// must be set before the proxy is first used.
 type LoggedIOProxy struct {
-	reportReadEvent  func(readContents []byte)
-	reportWriteEvent func(writeContents []byte)
-	reportCloseEvent func()
-	reportErrorEvent func(location string, err error)
-	proxiedObject    interface{}
-	location         string
+	// Accessed atomically, so must be first for 64-bit alignment.
+	sequences   [eventTypeCount]uint64
+	completions uint64
+
+	// If true, a one-line session summary is reported as a notification when
+	// Close() is called.
+	SummaryOnClose bool
+
+	// If true along with SummaryOnClose, the summary is followed by the
+	// distribution of read sizes (see ReadSizeHistogram()), for example
+	// "LoggedIO: Read sizes: <=1B:1 <=4B:2".
+	ReadSizesInSummary bool
+
+	// The format of the session summary. It must contain a %v for the total
+	// bytes read, the total bytes written, the number of errors, and the
+	// session duration (see Lifetime()), in that order.
+	SummaryFormat string
+
+	// If true, the session's lifetime (see Lifetime()) is measured from the
+	// first byte read or written rather than from when the proxy was created.
+	// If no data is ever transferred, it's measured from creation.
+	LifetimeFromFirstActivity bool
+
+	// If true, io.EOF returned from Read() is treated as the normal end of the
+	// stream rather than being reported as an error.
+	SuppressEOF bool
+
+	// If true, the first io.EOF returned from Read() on a net.Conn that hasn't
+	// been closed is reported as the notification "LoggedIO: half-close (peer
+	// closed read side)" instead of as an error, and later ones aren't
+	// reported at all. The connection can usually still be written to (see
+	// IsHalfClosed()).
+	ReportHalfClose bool
+
+	// If true, calls to Write() with an empty buffer are still passed to the
+	// proxied object, but are otherwise treated as no-ops: nothing is
+	// reported about them, including any error they return. Some writers
+	// reject empty writes (often issued as flush triggers) with an error,
+	// which is just noise in the log.
+	IgnoreEmptyWrites bool
+
+	// If true, reporting goes quiet once an error has been reported: the
+	// error itself is reported as usual, but nothing after it is (apart from
+	// close events) until ResumeAfterError() is called. This keeps a
+	// repeatedly failing connection from flooding the log. Stats are still
+	// counted.
+	StopLoggingAfterError bool
+
+	// If greater than 0, text output prefixes each read's payload with up to
+	// this many of its first bytes as hex (for example "R [peek=0102 ...]"),
+	// to identify message types at a glance even when the rest is truncated
+	// (see MaxLogBytes), hashed (see HashPayloads), or rendered in a hard to
+	// read encoding.
+	PeekBytes int
+
+	// The labels used for the read and write directions in direction-tagged
+	// output (such as JSON events).
+	DirectionLabels DirectionLabels
+
+	// If true, write payloads are reported BEFORE being passed to the proxied
+	// object, so that the contents of a write that blocks or panics are still
+	// visible. Errors are still reported afterwards.
+	ReportBeforeWrite bool
+
+	// If true, calling a method that the proxied object doesn't implement
+	// reports and returns a descriptive error instead of panicking.
+	RecoverMethodMismatch bool
+
+	// If true, timeout errors (net.Error with Timeout() == true) aren't
+	// reported at all. This is useful when deadlines are used for polling.
+	SuppressTimeouts bool
+
+	// If true, read, write, error, close, and notify events each have their
+	// own sequence, rather than sharing a single sequence.
+	SeparateSequences bool
+
+	// If true, text output passes the event's sequence number as an extra
+	// first argument to the read, write, and error formats, which must then
+	// have a leading %v for it (for example "%v R [%v]"). Close messages and
+	// notifications are printed without a number, though they still use one
+	// up.
+	SequenceInText bool
+
+	// If true, the first read following a write is annotated with the time
+	// elapsed since that write, which surfaces request/response round trip
+	// latency. The time is measured from when the write COMPLETED (returned
+	// from the proxied object), not from when it started. Text output passes
+	// the annotation (for example "(+2.3ms since write) ", or "" if there's
+	// nothing to annotate) as an extra leading argument to the read format
+	// (following the sequence number if present), which must then have a %v
+	// for it (for example "R %v[%v]").
+	ReportWriteToReadLatency bool
+
+	// If nonzero, read and write payloads are reported as their length and
+	// digest using this hash algorithm, instead of their contents (for example
+	// "len=4 sha256=9f86d081..."). This provides an audit trail without
+	// exposing sensitive data. SHA-224, SHA-256, SHA-384 and SHA-512 are
+	// available; other algorithms must have their implementing package
+	// imported.
+	HashPayloads crypto.Hash
+
+	// If greater than 0, progress is reported each time the total bytes read
+	// and written crosses a multiple of this value (see SetProgressCallback).
+	ProgressEvery int64
+
+	// If true, read and write events are numbered in the order that their I/O
+	// calls completed (see Event.Completion), which can differ from the order
+	// they're reported in when reads and writes happen concurrently.
+	TrackCompletionOrder bool
+
+	// If true, each call to SetDeadline(), SetReadDeadline(), or
+	// SetWriteDeadline() is reported as a notification showing the deadline
+	// as both an absolute time and relative to now (according to the proxy's
+	// clock), for example:
+	//
+	//	LoggedIO: SetReadDeadline() 2020-01-01T00:00:05Z (+5s)
+	//
+	// The zero time is reported as "no deadline".
+	ReportDeadlines bool
+
+	// If not empty, text output renders empty payloads as this token (for
+	// example "<empty>") rather than as nothing, so that they stand out.
+	EmptyPayloadToken string
+
+	// If true, the read stream is treated as TLS, and each TLS record header
+	// read is reported as a notification after the read that completed it,
+	// for example "LoggedIO: TLS handshake len=512 version=3.1". This is best
+	// effort: if the stream turns out not to be TLS, it's reported once and
+	// then ignored.
+	TLSRecordAware bool
+
+	// If true, both directions are treated as streams of WebSocket frames, and
+	// each complete frame is decoded and reported as a notification after the
+	// read or write that completed it, such as:
+	//
+	//	LoggedIO: recv TEXT fin=1 len=12 "hello world!"
+	//
+	// Frames split across reads or writes are buffered until complete. Raw
+	// data is still reported as usual (set its format to "" to see only the
+	// decoded frames).
+	WebSocketAware bool
+
+	// If true, a failed Close() is reported only as an error, without the
+	// close event that would normally precede it.
+	ErrorInsteadOfCloseOnFailure bool
+
+	// If not empty, identifies the proxy in its output: text output prefixes
+	// every event with the label and a space, and JSON output stores it in the
+	// "label" field.
+	Label string
+
+	// If greater than 0, a notification such as "LoggedIO: Warning: Write() of
+	// 100 bytes is larger than 50" is reported whenever Write() is handed more
+	// than this many bytes. The data is still written as normal.
+	WarnWriteLargerThan int
+
+	// If true, when a read or write returns both data and an error, the text
+	// and JSON outputs report them together as one event (for example
+	// "R [abc] (then error: EOF)") rather than as a data event followed by an
+	// error event. Other outputs still receive a separate error event. This
+	// doesn't apply to writes when ReportBeforeWrite is set, or when writes
+	// are being coalesced.
+	CombineDataAndError bool
+
+	// If greater than 0, a notification such as "LoggedIO: idle (no traffic
+	// for 30s)" is reported at this interval whenever nothing has been read or
+	// written for at least this long. The checks run in the background from
+	// the first Read() or Write() call until Close(), using the proxy's clock
+	// (see TickerClock).
+	IdleMarkerInterval time.Duration
+
+	// If greater than 0, a read that returns data more than this long after
+	// the previous read that returned data is preceded by a notification such
+	// as "LoggedIO: Read gap=5.2s (stall?)", to expose pauses in the incoming
+	// stream. Gaps are measured using the proxy's clock.
+	ReadGapThreshold time.Duration
+
+	// If true, a notification is reported whenever a read fills the caller's
+	// entire buffer, which usually means that more data was available and the
+	// stream is being fragmented by an undersized buffer. Such reads are
+	// always counted in Stats().BufferLimitedReads.
+	ReportBufferLimitedReads bool
+
+	// If greater than 0, formatted read and write payloads longer than this
+	// many bytes are truncated, keeping the part selected by TruncateMode and
+	// noting how many bytes were omitted. This doesn't affect hashed payloads
+	// or proxies that report raw data (such as DumpToWriters and JSON).
+	MaxLogBytes int
+
+	// Which part of a payload to keep when truncating it to MaxLogBytes.
+	// Defaults to TruncateHead.
+	TruncateMode TruncateMode
+
+	// How carriage returns and line feeds in string encoded payloads are
+	// rendered. Defaults to LineEndingsAsIs.
+	LineEndings LineEndingStyle
+
+	// If greater than 0, base64 encoded payloads are split into lines of at
+	// most this many characters (76 for MIME), separated by
+	// Base64LineTerminator (or "\n" if that's empty). Defaults to 0 (a single
+	// line).
+	Base64LineLength     int
+	Base64LineTerminator string
+
+	// If set, the temporary buffers used to copy payloads for synchronous
+	// report targets (mirrors and pcap records) are taken from and returned to
+	// this pool, reducing GC pressure on high-throughput proxies. The pool's
+	// New function (if any) must return a *[]byte. See NewBufferPool().
+	//
+	// A buffer is returned to the pool as soon as the write to its target
+	// completes, so targets must not retain the slices passed to them (as the
+	// io.Writer contract already requires). Copies kept past the report (as
+	// by MemorySink, EventQueue, and CaptureReplay) are never pooled.
+	BufferPool *sync.Pool
+
+	// If true, the stack of the goroutine that made the failing call is
+	// captured on the proxy's first error, and reported as a notification
+	// right after it ("LoggedIO: Stack for error at Read(): ..."). Capturing
+	// stacks is expensive, so this is off by default.
+	CaptureErrorStack bool
+
+	// If true along with CaptureErrorStack, stacks are captured on every error
+	// rather than only the first.
+	CaptureAllErrorStacks bool
+
+	// If greater than 0, proxies built by DumpToWriters (and the DumpToFiles
+	// variants) also write a preview of each read and write to the notify
+	// writer, showing its length and up to this many of its first bytes as
+	// hex (for example "read 300 bytes: 16 03 01…"). This allows following
+	// along without tailing the dump files.
+	PreviewBytes int
+
+	// If true, proxies built by DumpToWriters (and the DumpToFiles variants)
+	// write one event at a time, so that concurrent reads and writes can't
+	// interleave their output. This is needed when separate writers share a
+	// backing store (such as two handles to the same file). It's enabled
+	// automatically when the same writer is passed more than once.
+	SerializeOutput bool
+
+	// If greater than 0, the maximum number of bytes that may be read and
+	// written in total. The call that exceeds the budget completes normally
+	// and is followed by a "LoggedIO: Byte budget of N exceeded" notification,
+	// but all subsequent calls to Read() and Write() fail with
+	// ErrBudgetExceeded (reported as an error) without reaching the proxied
+	// object.
+	TotalByteBudget int64
+
+	// The fraction (0 to 1) of a payload's bytes that must be text (printable
+	// UTF-8, tab, CR, or LF) for EncodingAuto and EncodingAutoDump to render
+	// it as a string rather than as hex. Defaults to DefaultTextThreshold,
+	// which tolerates the odd control byte in an otherwise textual protocol.
+	// Set it to 1 to require payloads to be entirely text.
+	TextThreshold float64
+
+	// If greater than 0, the number of read and write calls made during each
+	// interval of this length, along with their average size, are reported
+	// as a notification such as "LoggedIO: reads=1024 calls in 1m0s
+	// avg=16B/call, writes=2 calls in 1m0s avg=512B/call". Many small calls
+	// suggest missing buffering. Reports run in the background from the first
+	// Read() or Write() call until Close(), using the proxy's clock (see
+	// TickerClock).
+	ReportCallStatsEvery time.Duration
+
+	// If set, proxies built by DumpToWriters (and the DumpToFiles variants)
+	// pass each read and write payload through this function, and dump the
+	// result instead (for example to strip framing headers or to decode
+	// obfuscated data). The function receives a copy of the payload, which it
+	// may modify and return. The proxied object always sees the original data.
+	DumpTransform func(payload []byte) []byte
+
+	// Prepended as-is to the location of every reported error, to tell apart
+	// the errors of proxies that share a report target. For example, with a
+	// prefix of "ctrl: ", a failed read is reported at "ctrl: Read()".
+	LocationPrefix string
+
+	// If set, the location of every reported error (such as "Read()", "Read()
+	// timeout", or "SetReadDeadline()") is passed through this function
+	// before LocationPrefix is prepended, for example to shorten it.
+	LocationMapper func(location string) string
+
+	// Whether the formatting proxy generators (StringToWriter, HexToLog, etc)
+	// color each event's output with ANSI escape codes, according to the
+	// Colors option. Defaults to ColorNever.
+	Color ColorMode
+
+	// The colors used when the Color option is enabled. Defaults to
+	// DefaultColorScheme.
+	Colors ColorScheme
+
+	// If set, called with the direction and payload of every read and write
+	// before it's reported. Returning false skips reporting (and mirroring)
+	// that payload. Stats are still counted, and any error returned along
+	// with the data is still reported on its own.
+	Filter func(direction Direction, data []byte) bool
+
+	// The number of most recent errors to keep for RecentErrors(). Defaults
+	// to 0 (no history is kept).
+	RecentErrorsCapacity int
+
+	// If either is greater than 0, every event is tagged with the phase of the
+	// connection it occurred in (see Phase): the handshake phase lasts for the
+	// first HandshakeBytes bytes transferred and for the first
+	// HandshakeDuration after the proxy was created, ending as soon as either
+	// limit is reached. The teardown phase starts once Read() returns io.EOF
+	// or Close() is called, and everything in between is the steady phase.
+	// Text output prefixes each event with its phase (after the label), and
+	// JSON output stores it in the "phase" field.
+	HandshakeBytes    int64
+	HandshakeDuration time.Duration
+
+	handleEvent       func(event *Event)
+	reportNotifyEvent func(message string)
+	proxiedObject     interface{}
+	location          string
+	closed            int32
+	sawReadEOF        int32
+	silencedByError   int32
+	autoReadDeadline  int64
+	encoding          int32
+	disabled          int32
+	loggingClosed     int32
+	ownedClosed       int32
+	ownedClosers      []io.Closer
+	clock             Clock
+	openedAt          time.Time
+	lastWriteAt       time.Time
+	isWritePending    bool
+	mutex             sync.Mutex
+	stats             Stats
+	lastError         error
+	readSizes         [readSizeBucketCount]int64
+	recentErrors      errorHistory
+	statsDelegate     *LoggedIOProxy
+	readFramer        readFramer
+	payloadMatchers   payloadMatchers
+	target            *reportTarget
+	formatter         *textFormatter
+	closeHooks        []func(err error)
+	readDeadline      time.Time
+	writeDeadline     time.Time
+	mirror            io.Writer
+	mirrorErr         error
+	mirrorMutex       sync.Mutex
+	reportProgress    func(transferred int64)
+	sinks             []sinkEntry
+	sinksMutex        sync.Mutex
+	nextSinkID        int
+	tlsRecords        tlsRecordTracker
+	webSocketReads    webSocketFrameDecoder
+	webSocketWrites   webSocketFrameDecoder
+	coalescer         writeCoalescer
+	combiner          writeCombiner
+	latency           *latencyHistograms
+	lastActivityAt    time.Time
+	firstActivityAt   time.Time
+	lastReadAt        time.Time
+	closedAt          time.Time
+	idle              periodicTask
+	callStats         periodicTask
+	lastCallStats     Stats
+	lastCallStatsAt   time.Time
+
+	reportFirstCloseOnly  bool
+	reportClassifiedError func(location string, err error, class ErrorClass)
+}
+
+// The default format used for session summaries.
+const DefaultSummaryFormat = "SUMMARY read=%v write=%v errors=%v dur=%v\n"
+
+// SetNotifyCallback sets the function that receives notification messages
+// (such as session summaries), overriding the proxy's normal notification
+// reporting. Proxies created via Generic() discard notifications unless this
+// is set.
+func (this *LoggedIOProxy) SetNotifyCallback(reportNotifyEvent func(message string)) {
+	this.reportNotifyEvent = reportNotifyEvent
+}
+
+// OnClose registers a function to be called when the proxy is first closed,
+// receiving the error returned by the proxied object's Close() (which may be
+// nil). Hooks are called in the reverse order of registration, after the close
+// has been reported.
+func (this *LoggedIOProxy) OnClose(hook func(err error)) {
+	this.mutex.Lock()
+	defer this.mutex.Unlock()
+	this.closeHooks = append(this.closeHooks, hook)
+}
+
+func (this *LoggedIOProxy) runCloseHooks(err error) {
+	this.mutex.Lock()
+	hooks := this.closeHooks
+	this.mutex.Unlock()
+	for i := len(hooks) - 1; i >= 0; i-- {
+		hooks[i](err)
+	}
+}
+
+// SetClock replaces the clock used for all time-based reporting, and restarts
+// the session timer from the new clock's current time.
+func (this *LoggedIOProxy) SetClock(clock Clock) {
+	this.clock = clock
+	this.openedAt = clock.Now()
+	this.lastActivityAt = this.openedAt
+	this.lastCallStatsAt = this.openedAt
+}
+
+// Unwrap returns the object being proxied.
+func (this *LoggedIOProxy) Unwrap() interface{} {
+	return this.proxiedObject
+}
+
+// LastError returns the most recently reported error, or nil if no error has
+// been reported.
+func (this *LoggedIOProxy) LastError() error {
+	this.mutex.Lock()
+	defer this.mutex.Unlock()
+	return this.lastError
+}
+
+// IsClosed returns true if Close() has been called on this proxy.
+func (this *LoggedIOProxy) IsClosed() bool {
+	return atomic.LoadInt32(&this.closed) != 0
 }
 
 func (this *LoggedIOProxy) Read(b []byte) (n int, err error) {
-	reader := this.proxiedObject.(io.Reader)
-	n, err = reader.Read(b)
+	reader, ok := this.proxiedObject.(io.Reader)
+	if err = this.checkImplements(ok, "Read()", "io.Reader"); err != nil {
+		return
+	}
+	if err = this.refuseOverBudget("Read()"); err != nil {
+		return
+	}
+	this.applyAutoReadDeadline()
+	this.startBackgroundTasks()
+	if this.latency != nil {
+		startedAt := this.clock.Now()
+		n, err = reader.Read(b)
+		this.latency.record(DirectionRead, this.clock.Now().Sub(startedAt))
+	} else {
+		n, err = reader.Read(b)
+	}
+	completion := this.nextCompletion()
+	this.countRead(n)
+	var errorEvent *Event
+	isFirstEOF := err == io.EOF && atomic.CompareAndSwapInt32(&this.sawReadEOF, 0, 1)
+	isHalfClose := err == io.EOF && this.isHalfCloseReported()
+	if err != nil && !(err == io.EOF && (this.SuppressEOF || isHalfClose)) {
+		errorEvent = this.newErrorEvent(this.locationAfterClose("Read()"), err)
+	}
 	if n > 0 {
-		this.reportReadEvent(b[:n])
+		this.checkReadGap()
+		this.reportRead(b[:n], completion, errorEvent)
+		this.readFramer.feed(b[:n])
+		this.payloadMatchers.feed(DirectionRead, b[:n])
+		if this.TLSRecordAware {
+			for _, annotation := range this.tlsRecords.feed(b[:n]) {
+				this.reportNotify(annotation)
+			}
+		}
+		if this.WebSocketAware {
+			for _, annotation := range this.webSocketReads.feed("recv", b[:n]) {
+				this.reportNotify(annotation)
+			}
+		}
 	}
-	if err != nil {
-		this.reportErrorEvent("Read()", err)
+	if n > 0 && n == len(b) && err == nil {
+		this.countBufferLimitedRead(n)
 	}
+	if isFirstEOF && isHalfClose {
+		this.reportNotify("LoggedIO: half-close (peer closed read side)\n")
+	}
+	this.reportErrorEvent(errorEvent)
 	return
 }
 
+// isHalfCloseReported returns true if io.EOF from Read() should be reported as
+// a half-close rather than as an error (see ReportHalfClose).
+func (this *LoggedIOProxy) isHalfCloseReported() bool {
+	_, isConn := this.proxiedObject.(net.Conn)
+	return this.ReportHalfClose && isConn && !this.IsClosed()
+}
+
+// IsHalfClosed returns true if Read() has returned io.EOF, meaning that the
+// peer has finished sending. Writing may still be possible.
+func (this *LoggedIOProxy) IsHalfClosed() bool {
+	return atomic.LoadInt32(&this.sawReadEOF) != 0
+}
+
 func (this *LoggedIOProxy) Write(b []byte) (n int, err error) {
-	writer := this.proxiedObject.(io.Writer)
-	n, err = writer.Write(b)
+	writer, ok := this.proxiedObject.(io.Writer)
+	if err = this.checkImplements(ok, "Write()", "io.Writer"); err != nil {
+		return
+	}
+	if err = this.refuseOverBudget("Write()"); err != nil {
+		return
+	}
+	this.startBackgroundTasks()
+	if this.WarnWriteLargerThan > 0 && len(b) > this.WarnWriteLargerThan {
+		this.reportNotify(fmt.Sprintf("LoggedIO: Warning: Write() of %v bytes is larger than %v\n",
+			len(b), this.WarnWriteLargerThan))
+	}
+	if this.combiner.isEnabled {
+		return this.combineWrite(writer, b)
+	}
+	return this.write(writer, b)
+}
+
+// write passes b on to writer, reporting the write and any resulting error.
+func (this *LoggedIOProxy) write(writer io.Writer, b []byte) (n int, err error) {
+	if this.ReportBeforeWrite && len(b) > 0 {
+		this.reportWrite(b, this.nextCompletion(), nil)
+	}
+	if this.latency != nil {
+		startedAt := this.clock.Now()
+		n, err = writer.Write(b)
+		this.latency.record(DirectionWrite, this.clock.Now().Sub(startedAt))
+	} else {
+		n, err = writer.Write(b)
+	}
+	completion := this.nextCompletion()
+	this.countWrite(n)
+	var errorEvent *Event
+	if err != nil && !(len(b) == 0 && this.IgnoreEmptyWrites) {
+		errorEvent = this.newErrorEvent(this.locationAfterClose("Write()"), err)
+	}
+	if n > 0 && !this.ReportBeforeWrite {
+		this.reportWrite(b[:n], completion, errorEvent)
+	}
 	if n > 0 {
-		this.reportWriteEvent(b[:n])
+		this.payloadMatchers.feed(DirectionWrite, b[:n])
 	}
-	if err != nil {
-		this.reportErrorEvent("Write()", err)
+	if n > 0 && this.WebSocketAware {
+		for _, annotation := range this.webSocketWrites.feed("send", b[:n]) {
+			this.reportNotify(annotation)
+		}
 	}
+	this.reportErrorEvent(errorEvent)
 	return
 }
 
 func (this *LoggedIOProxy) Close() (err error) {
-	closer := this.proxiedObject.(io.Closer)
+	closer, ok := this.proxiedObject.(io.Closer)
+	if err = this.checkImplements(ok, "Close()", "io.Closer"); err != nil {
+		return
+	}
+	flushErr := this.flushCombinedWrites()
+	defer func() {
+		if err == nil {
+			err = flushErr
+		}
+	}()
+	isFirstClose := atomic.CompareAndSwapInt32(&this.closed, 0, 1)
+	if isFirstClose {
+		this.mutex.Lock()
+		this.closedAt = this.clock.Now()
+		this.mutex.Unlock()
+	}
+	this.stopBackgroundTasks()
 	err = closer.Close()
-	this.reportCloseEvent()
+	if (isFirstClose || !this.reportFirstCloseOnly) &&
+		!(err != nil && this.ErrorInsteadOfCloseOnFailure) {
+		this.reportClose()
+	}
 	if err != nil {
-		this.reportErrorEvent("Close()", err)
+		this.reportError("Close()", err)
+	}
+	if isFirstClose && this.SummaryOnClose {
+		this.reportSummary()
+		if this.ReadSizesInSummary {
+			this.reportReadSizes()
+		}
+	}
+	if isFirstClose {
+		this.runCloseHooks(err)
+	}
+	if isFirstClose && this.target != nil {
+		this.target.close()
+	}
+	if isFirstClose {
+		this.closeOwnedWriters()
 	}
 	return
 }
 
 func (this *LoggedIOProxy) LocalAddr() net.Addr {
-	conn := this.proxiedObject.(net.Conn)
+	conn, ok := this.proxiedObject.(net.Conn)
+	if this.checkImplements(ok, "LocalAddr()", "net.Conn") != nil {
+		return nil
+	}
 	return conn.LocalAddr()
 }
 
 func (this *LoggedIOProxy) RemoteAddr() net.Addr {
-	conn := this.proxiedObject.(net.Conn)
+	conn, ok := this.proxiedObject.(net.Conn)
+	if this.checkImplements(ok, "RemoteAddr()", "net.Conn") != nil {
+		return nil
+	}
 	return conn.RemoteAddr()
 }
 
 func (this *LoggedIOProxy) SetDeadline(t time.Time) (err error) {
-	conn := this.proxiedObject.(net.Conn)
+	conn, ok := this.proxiedObject.(net.Conn)
+	if err = this.checkImplements(ok, "SetDeadline()", "net.Conn"); err != nil {
+		return
+	}
+	this.reportDeadline("SetDeadline()", t)
 	err = conn.SetDeadline(t)
 	if err != nil {
-		this.reportErrorEvent("SetDeadline()", err)
+		this.reportError("SetDeadline()", err)
+	} else {
+		this.rememberDeadlines(&t, &t)
 	}
 	return
 }
 
 func (this *LoggedIOProxy) SetReadDeadline(t time.Time) (err error) {
-	conn := this.proxiedObject.(net.Conn)
+	conn, ok := this.proxiedObject.(net.Conn)
+	if err = this.checkImplements(ok, "SetReadDeadline()", "net.Conn"); err != nil {
+		return
+	}
+	this.reportDeadline("SetReadDeadline()", t)
 	err = conn.SetReadDeadline(t)
 	if err != nil {
-		this.reportErrorEvent("SetReadDeadline()", err)
+		this.reportError("SetReadDeadline()", err)
+	} else {
+		this.rememberDeadlines(&t, nil)
 	}
 	return
 }
 
 func (this *LoggedIOProxy) SetWriteDeadline(t time.Time) (err error) {
-	conn := this.proxiedObject.(net.Conn)
+	conn, ok := this.proxiedObject.(net.Conn)
+	if err = this.checkImplements(ok, "SetWriteDeadline()", "net.Conn"); err != nil {
+		return
+	}
+	this.reportDeadline("SetWriteDeadline()", t)
 	err = conn.SetWriteDeadline(t)
 	if err != nil {
-		this.reportErrorEvent("SetWriteDeadline()", err)
+		this.reportError("SetWriteDeadline()", err)
+	} else {
+		this.rememberDeadlines(nil, &t)
 	}
 	return
 }
 
+// SetReadTimeout sets the read deadline of the underlying net.Conn to d from
+// now (according to the proxy's clock), and reports the timeout. A zero
+// duration clears the deadline.
+func (this *LoggedIOProxy) SetReadTimeout(d time.Duration) error {
+	this.reportTimeout("SetReadTimeout()", d)
+	return this.SetReadDeadline(this.deadlineAfter(d))
+}
+
+// SetWriteTimeout sets the write deadline of the underlying net.Conn to d from
+// now (according to the proxy's clock), and reports the timeout. A zero
+// duration clears the deadline.
+func (this *LoggedIOProxy) SetWriteTimeout(d time.Duration) error {
+	this.reportTimeout("SetWriteTimeout()", d)
+	return this.SetWriteDeadline(this.deadlineAfter(d))
+}
+
+// AutoReadDeadline makes the proxy set the read deadline of the underlying
+// net.Conn to d from now (according to the proxy's clock) before each Read(),
+// reporting each deadline as a notification such as "LoggedIO: Auto read
+// deadline 2020-01-01T00:00:05Z (+5s)". This replaces the common pattern of
+// calling SetReadDeadline() before every read. A duration of 0 stops setting
+// deadlines (leaving the last one in place). It does nothing if the proxied
+// object isn't a net.Conn. It's safe to call concurrently with I/O.
+func (this *LoggedIOProxy) AutoReadDeadline(d time.Duration) {
+	atomic.StoreInt64(&this.autoReadDeadline, int64(d))
+}
+
+func (this *LoggedIOProxy) applyAutoReadDeadline() {
+	d := time.Duration(atomic.LoadInt64(&this.autoReadDeadline))
+	if d <= 0 {
+		return
+	}
+	conn, ok := this.proxiedObject.(net.Conn)
+	if !ok {
+		return
+	}
+	deadline := this.clock.Now().Add(d)
+	this.reportNotify(fmt.Sprintf("LoggedIO: Auto read deadline %v (+%v)\n",
+		deadline.Format(time.RFC3339Nano), d))
+	if err := conn.SetReadDeadline(deadline); err != nil {
+		this.reportError("SetReadDeadline()", err)
+	} else {
+		this.rememberDeadlines(&deadline, nil)
+	}
+}
+
+func (this *LoggedIOProxy) deadlineAfter(d time.Duration) time.Time {
+	if d == 0 {
+		return time.Time{}
+	}
+	return this.clock.Now().Add(d)
+}
+
+func (this *LoggedIOProxy) reportTimeout(location string, d time.Duration) {
+	if d == 0 {
+		this.reportNotify(fmt.Sprintf("LoggedIO: %v no timeout\n", location))
+		return
+	}
+	this.reportNotify(fmt.Sprintf("LoggedIO: %v %v\n", location, d))
+}
+
+func (this *LoggedIOProxy) checkImplements(implements bool, location string, interfaceName string) error {
+	if implements {
+		return nil
+	}
+	err := fmt.Errorf("LoggedIO: proxied object of type %T does not implement %v", this.proxiedObject, interfaceName)
+	if !this.RecoverMethodMismatch {
+		panic(err)
+	}
+	this.reportError(location, err)
+	return err
+}
+
+func (this *LoggedIOProxy) countRead(n int) {
+	this.mutex.Lock()
+	before := this.stats.BytesRead + this.stats.BytesWritten
+	this.stats.BytesRead += int64(n)
+	this.stats.ReadCalls++
+	this.readSizes[readSizeBucket(n)]++
+	if n > 0 && this.firstActivityAt.IsZero() {
+		this.firstActivityAt = this.clock.Now()
+	}
+	if n > 0 && this.IdleMarkerInterval > 0 {
+		this.lastActivityAt = this.clock.Now()
+	}
+	this.mutex.Unlock()
+	this.progress(before, before+int64(n))
+	this.checkBudget(before, before+int64(n))
+}
+
+// countBufferLimitedRead records a read that filled the caller's entire
+// buffer, which suggests that more data was available.
+func (this *LoggedIOProxy) countBufferLimitedRead(n int) {
+	this.mutex.Lock()
+	this.stats.BufferLimitedReads++
+	this.mutex.Unlock()
+	if this.ReportBufferLimitedReads {
+		this.reportNotify(fmt.Sprintf("LoggedIO: Read() filled its %v byte buffer\n", n))
+	}
+}
+
+func (this *LoggedIOProxy) countWrite(n int) {
+	this.mutex.Lock()
+	before := this.stats.BytesRead + this.stats.BytesWritten
+	this.stats.BytesWritten += int64(n)
+	this.stats.WriteCalls++
+	if n > 0 && this.firstActivityAt.IsZero() {
+		this.firstActivityAt = this.clock.Now()
+	}
+	if n > 0 && this.IdleMarkerInterval > 0 {
+		this.lastActivityAt = this.clock.Now()
+	}
+	if this.ReportWriteToReadLatency && n > 0 {
+		this.lastWriteAt = this.clock.Now()
+		this.isWritePending = true
+	}
+	this.mutex.Unlock()
+	this.progress(before, before+int64(n))
+	this.checkBudget(before, before+int64(n))
+}
+
+// assignSequence gives an event the next sequence number, unless it already
+// has one.
+func (this *LoggedIOProxy) assignSequence(event *Event) {
+	if event.Sequence != 0 {
+		return
+	}
+	eventType := event.Type
+	if !this.SeparateSequences {
+		eventType = 0
+	}
+	event.Sequence = atomic.AddUint64(&this.sequences[eventType], 1)
+}
+
+func (this *LoggedIOProxy) reportDeadline(location string, deadline time.Time) {
+	if !this.ReportDeadlines {
+		return
+	}
+	if deadline.IsZero() {
+		this.reportNotify(fmt.Sprintf("LoggedIO: %v no deadline\n", location))
+		return
+	}
+	relative := deadline.Sub(this.clock.Now())
+	sign := "+"
+	if relative < 0 {
+		sign = ""
+	}
+	this.reportNotify(fmt.Sprintf("LoggedIO: %v %v (%v%v)\n",
+		location, deadline.Format(time.RFC3339Nano), sign, relative))
+}
+
+// rememberDeadlines records the deadlines most recently set via the proxy,
+// since net.Conn has no way to query them. A nil argument leaves that deadline
+// unchanged.
+func (this *LoggedIOProxy) rememberDeadlines(readDeadline, writeDeadline *time.Time) {
+	this.mutex.Lock()
+	defer this.mutex.Unlock()
+	if readDeadline != nil {
+		this.readDeadline = *readDeadline
+	}
+	if writeDeadline != nil {
+		this.writeDeadline = *writeDeadline
+	}
+}
+
+func (this *LoggedIOProxy) report(event *Event) {
+	if !this.IsEnabled() || this.isSilencedByError(event.Type) {
+		return
+	}
+	if event.Type != EventWrite && this.coalescer.window > 0 {
+		this.flushCoalescedWrites()
+	}
+	this.mutex.Lock()
+	event.ReadDeadline = this.readDeadline
+	event.WriteDeadline = this.writeDeadline
+	event.Phase = this.phaseOf(event)
+	this.mutex.Unlock()
+	this.assignSequence(event)
+	this.reportToSinks(event)
+	if event.Type == EventNotify && this.reportNotifyEvent != nil {
+		this.reportNotifyEvent(event.Message)
+		return
+	}
+	if event.Type == EventError && this.reportClassifiedError != nil {
+		this.reportClassifiedError(event.Location, event.Err, ClassifyError(event.Err))
+		return
+	}
+	this.handleEvent(event)
+}
+
+// nextCompletion returns the next completion order number, or 0 if completion
+// order isn't being tracked.
+func (this *LoggedIOProxy) nextCompletion() uint64 {
+	if !this.TrackCompletionOrder {
+		return 0
+	}
+	return atomic.AddUint64(&this.completions, 1)
+}
+
+// combineError attaches the error that was returned along with a data event's
+// data to the data event, if CombineDataAndError is set.
+func (this *LoggedIOProxy) combineError(event *Event, errorEvent *Event) {
+	if this.CombineDataAndError && errorEvent != nil {
+		event.Location = errorEvent.Location
+		event.Err = errorEvent.Err
+		errorEvent.ReportedWithData = true
+	}
+}
+
+func (this *LoggedIOProxy) reportRead(b []byte, completion uint64, errorEvent *Event) {
+	if !this.IsEnabled() || this.isSilencedByError(EventRead) || !this.passesFilter(DirectionRead, b) {
+		return
+	}
+	event := &Event{Type: EventRead, Data: b, Completion: completion}
+	this.combineError(event, errorEvent)
+	if this.ReportWriteToReadLatency {
+		this.mutex.Lock()
+		if this.isWritePending {
+			event.FollowsWrite = true
+			event.SinceWrite = this.clock.Now().Sub(this.lastWriteAt)
+			this.isWritePending = false
+		}
+		this.mutex.Unlock()
+	}
+	this.report(event)
+	this.mirrorPayload(MirrorTagInbound, b)
+}
+
+func (this *LoggedIOProxy) reportWrite(b []byte, completion uint64, errorEvent *Event) {
+	if !this.IsEnabled() || this.isSilencedByError(EventWrite) || !this.passesFilter(DirectionWrite, b) {
+		return
+	}
+	if this.coalescer.window > 0 {
+		this.coalesceWrite(b, completion)
+	} else {
+		event := &Event{Type: EventWrite, Data: b, Completion: completion}
+		this.combineError(event, errorEvent)
+		this.report(event)
+	}
+	this.mirrorPayload(MirrorTagOutbound, b)
+}
+
+func (this *LoggedIOProxy) reportError(location string, err error) {
+	this.reportErrorEvent(this.newErrorEvent(location, err))
+}
+
+// newErrorEvent returns the error event to report for err, or nil if it
+// shouldn't be reported.
+func (this *LoggedIOProxy) newErrorEvent(location string, err error) *Event {
+	if isTimeout(err) {
+		if this.SuppressTimeouts {
+			return nil
+		}
+		location += " timeout"
+	}
+	if this.LocationMapper != nil {
+		location = this.LocationMapper(location)
+	}
+	return &Event{Type: EventError, Location: this.LocationPrefix + location, Err: err}
+}
+
+func (this *LoggedIOProxy) reportErrorEvent(event *Event) {
+	if event == nil {
+		return
+	}
+	this.mutex.Lock()
+	isFirstError := this.stats.Errors == 0
+	this.stats.Errors++
+	this.lastError = event.Err
+	this.recentErrors.add(this.RecentErrorsCapacity,
+		ErrorEvent{Location: event.Location, Err: event.Err, Time: this.clock.Now()})
+	this.mutex.Unlock()
+	this.report(event)
+	this.reportErrorStack(event.Location, isFirstError)
+	if this.StopLoggingAfterError {
+		atomic.StoreInt32(&this.silencedByError, 1)
+	}
+}
+
+func (this *LoggedIOProxy) reportClose() {
+	this.report(&Event{Type: EventClose})
+}
+
+func (this *LoggedIOProxy) reportNotify(message string) {
+	this.report(&Event{Type: EventNotify, Message: message})
+}
+
+func (this *LoggedIOProxy) reportSummary() {
+	stats := this.Stats()
+	this.reportNotify(fmt.Sprintf(this.SummaryFormat,
+		stats.BytesRead, stats.BytesWritten, stats.Errors, this.Lifetime()))
+}
+
+// Lifetime returns how long the session lasted (according to the proxy's
+// clock), from the proxy's creation (or the first data transferred, if the
+// LifetimeFromFirstActivity option is set) until the first Close(). If the
+// proxy hasn't been closed yet, it returns the lifetime so far.
+func (this *LoggedIOProxy) Lifetime() time.Duration {
+	this.mutex.Lock()
+	defer this.mutex.Unlock()
+	start := this.openedAt
+	if this.LifetimeFromFirstActivity && !this.firstActivityAt.IsZero() {
+		start = this.firstActivityAt
+	}
+	end := this.closedAt
+	if end.IsZero() {
+		end = this.clock.Now()
+	}
+	return end.Sub(start)
+}
+
+func isTimeout(err error) bool {
+	netErr, ok := err.(net.Error)
+	return ok && netErr.Timeout()
+}
+
+func (this *LoggedIOProxy) locationAfterClose(location string) string {
+	if atomic.LoadInt32(&this.closed) != 0 {
+		return location + " after close"
+	}
+	return location
+}
+
 var hexDigits = []byte{
 	'0', '1', '2', '3', '4', '5', '6', '7', '8', '9', 'a', 'b', 'c', 'd', 'e', 'f',
 }
@@ -266,11 +1325,358 @@ func writerForFile(filename string) io.Writer {
 	}
 }
 
-func byteFunc(format string, function func([]byte)) func([]byte) {
+func lazyWriterForFile(filename string) io.Writer {
+	switch filename {
+	case "stdout", "stderr", "null":
+		return writerForFile(filename)
+	default:
+		return &lazyFileWriter{filename: filename}
+	}
+}
+
+// lazyFileWriter creates its file on the first write.
+type lazyFileWriter struct {
+	mutex    sync.Mutex
+	filename string
+	writer   io.Writer
+}
+
+func (this *lazyFileWriter) Write(b []byte) (n int, err error) {
+	this.mutex.Lock()
+	defer this.mutex.Unlock()
+	if this.writer == nil {
+		this.writer = writerForFile(this.filename)
+	}
+	return this.writer.Write(b)
+}
+
+// Close closes the file if it was created. Writes after closing fail.
+func (this *lazyFileWriter) Close() error {
+	this.mutex.Lock()
+	defer this.mutex.Unlock()
+	writer := this.writer
+	this.writer = closedWriter{}
+	if file, ok := writer.(*os.File); ok {
+		return file.Close()
+	}
+	return nil
+}
+
+// closedWriter fails all writes with os.ErrClosed.
+type closedWriter struct{}
+
+func (this closedWriter) Write(b []byte) (n int, err error) {
+	return 0, os.ErrClosed
+}
+
+func newProxy(proxiedObject interface{}, handleEvent func(event *Event)) *LoggedIOProxy {
+	this := new(LoggedIOProxy)
+	this.proxiedObject = proxiedObject
+	this.handleEvent = handleEvent
+	this.SummaryFormat = DefaultSummaryFormat
+	this.TextThreshold = DefaultTextThreshold
+	this.Colors = DefaultColorScheme
+	this.DirectionLabels = DefaultDirectionLabels
+	this.SetClock(systemClock{})
+	this.SetEnabled(enabledFromEnvironment())
+	return this
+}
+
+// textFormatter reports events as formatted text, either via a printf style
+// function (such as log.Printf), or to a writer.
+type textFormatter struct {
+	// Held for the duration of each event, so that events written in multiple
+	// pieces (such as streamed hex) can't be interleaved with other events.
+	mutex    sync.Mutex
+	proxy    *LoggedIOProxy
+	printf   func(format string, args ...interface{})
+	writer   io.Writer
+	readFmt  string
+	writeFmt string
+	errorFmt string
+	closeMsg string
+
+	// If set, these replace printf for read and write events respectively.
+	readPrintf  func(format string, args ...interface{})
+	writePrintf func(format string, args ...interface{})
+
+	// If mixedEncodings is true, reads and writes are rendered using these
+	// encodings rather than the proxy's encoding.
+	mixedEncodings bool
+	readEncoding   Encoding
+	writeEncoding  Encoding
+
+	// If true, the formats contain no verbs for leading arguments (such as
+	// sequence numbers), so they are prepended as needed.
+	addLeadingVerbs bool
+
+	// Where the formatter reports to, as returned by Config().
+	targetDescription string
+
+	// The phase of the event being handled.
+	phase Phase
+}
+
+func newTextProxy(proxiedObject interface{}, printf func(format string, args ...interface{}), writer io.Writer,
+	encoding Encoding, readFmt, writeFmt, errorFmt, closeMsg string) *LoggedIOProxy {
+	return newTextFormatter(proxiedObject, printf, writer, encoding,
+		readFmt, writeFmt, errorFmt, closeMsg).proxy
+}
+
+func newTextFormatter(proxiedObject interface{}, printf func(format string, args ...interface{}), writer io.Writer,
+	encoding Encoding, readFmt, writeFmt, errorFmt, closeMsg string) *textFormatter {
+	formatter := &textFormatter{
+		printf:   printf,
+		readFmt:  readFmt,
+		writeFmt: writeFmt,
+		errorFmt: errorFmt,
+		closeMsg: closeMsg,
+	}
+	formatter.proxy = newProxy(proxiedObject, formatter.handleEvent)
+	formatter.proxy.formatter = formatter
+	formatter.proxy.SetEncoding(encoding)
+	if writer != nil {
+		formatter.proxy.target = newReportTarget(writer)
+		formatter.writer = formatter.proxy.target
+		formatter.targetDescription = fmt.Sprintf("writer %T", writer)
+	} else {
+		formatter.targetDescription = "log"
+	}
+	return formatter
+}
+
+// SetReadFormat changes the format used to report subsequent reads. An empty
+// format disables read reporting.
+//
+// The format setters only affect proxies built by the formatting proxy
+// generators (StringToLog, HexToLog, StringToWriter, HexToWriter, ToTestLog),
+// and do nothing otherwise. They're safe to call concurrently with I/O.
+func (this *LoggedIOProxy) SetReadFormat(format string) {
+	this.setFormat(func(formatter *textFormatter) { formatter.readFmt = format })
+}
+
+// SetWriteFormat changes the format used to report subsequent writes. An empty
+// format disables write reporting.
+func (this *LoggedIOProxy) SetWriteFormat(format string) {
+	this.setFormat(func(formatter *textFormatter) { formatter.writeFmt = format })
+}
+
+// SetErrorFormat changes the format used to report subsequent errors. An empty
+// format disables error reporting.
+func (this *LoggedIOProxy) SetErrorFormat(format string) {
+	this.setFormat(func(formatter *textFormatter) { formatter.errorFmt = format })
+}
+
+// SetCloseMessage changes the message reported on subsequent closes. An empty
+// message disables close reporting.
+func (this *LoggedIOProxy) SetCloseMessage(message string) {
+	this.setFormat(func(formatter *textFormatter) { formatter.closeMsg = message })
+}
+
+func (this *LoggedIOProxy) setFormat(set func(formatter *textFormatter)) {
+	if this.formatter == nil {
+		return
+	}
+	this.formatter.mutex.Lock()
+	set(this.formatter)
+	this.formatter.mutex.Unlock()
+}
+
+func (this *textFormatter) handleEvent(event *Event) {
+	this.mutex.Lock()
+	defer this.mutex.Unlock()
+	this.phase = event.Phase
+	switch event.Type {
+	case EventRead:
+		this.printPayload(this.readFmt, event)
+	case EventWrite:
+		this.printPayload(this.writeFmt, event)
+	case EventError:
+		if !event.ReportedWithData {
+			this.printEvent(this.errorFmt, event, event.Location, event.Err)
+		}
+	case EventClose:
+		if this.closeMsg != "" {
+			this.print(event.Type, "%v", this.closeMsg)
+		}
+	case EventNotify:
+		this.print(event.Type, "%v", event.Message)
+	}
+}
+
+func (this *textFormatter) printPayload(format string, event *Event) {
 	if format == "" {
-		return func([]byte) {}
+		return
 	}
-	return function
+	if event.Err != nil {
+		format = withErrorSuffix(format, event.Err)
+	}
+	if len(event.Data) == 0 && this.proxy.EmptyPayloadToken != "" {
+		this.printEvent(format, event, this.proxy.EmptyPayloadToken)
+		return
+	}
+	peek := this.peek(event)
+	if this.proxy.HashPayloads != 0 {
+		this.printEvent(format, event, peek+describeHashedPayload(this.proxy.HashPayloads, event.Data))
+		return
+	}
+	encoding := this.encodingFor(event.Type)
+	if this.proxy.MaxLogBytes > 0 && len(event.Data) > this.proxy.MaxLogBytes {
+		this.printEvent(format, event,
+			peek+truncatePayload(this.encoder(encoding), event.Data, this.proxy.MaxLogBytes, this.proxy.TruncateMode))
+		return
+	}
+	if encoding == EncodingHex && this.writer != nil && len(event.Data) > hexStreamingThreshold {
+		leadingArgs := this.leadingArgs(event)
+		format = this.withLeadingVerbs(format, event)
+		if prefix, suffix, ok := splitFormatAtArg(format, len(leadingArgs)); ok {
+			color := this.colorFor(event.Type)
+			if _, err := fmt.Fprintf(this.writer, color+this.labelFormat()+prefix, leadingArgs...); err != nil {
+				return
+			}
+			if _, err := io.WriteString(this.writer, peek); err != nil {
+				return
+			}
+			if err := writeHex(this.writer, event.Data); err != nil {
+				return
+			}
+			io.WriteString(this.writer, withColorEnd(color, suffix))
+			return
+		}
+	}
+	this.printEvent(format, event, peek+this.encoder(encoding)(event.Data))
+}
+
+// peek returns the PeekBytes prefix for event's payload, or "" if there is
+// none.
+func (this *textFormatter) peek(event *Event) string {
+	if this.proxy.PeekBytes <= 0 || event.Type != EventRead || len(event.Data) == 0 {
+		return ""
+	}
+	peeked := event.Data
+	if len(peeked) > this.proxy.PeekBytes {
+		peeked = peeked[:this.proxy.PeekBytes]
+	}
+	return "peek=" + hex.EncodeToString(peeked) + " "
+}
+
+// encoder returns the function used to render payloads in encoding, taking
+// the proxy's rendering options into account.
+func (this *textFormatter) encoder(encoding Encoding) func([]byte) string {
+	if encoding == EncodingString && this.proxy.LineEndings != LineEndingsAsIs {
+		style := this.proxy.LineEndings
+		return func(b []byte) string { return style.render(string(b)) }
+	}
+	if encoding == EncodingAuto {
+		threshold := this.proxy.TextThreshold
+		return func(b []byte) string { return encodeAuto(b, threshold) }
+	}
+	if encoding == EncodingAutoDump {
+		threshold := this.proxy.TextThreshold
+		return func(b []byte) string { return encodeAutoDump(b, threshold) }
+	}
+	if encoding == EncodingBase64 && this.proxy.Base64LineLength > 0 {
+		lineLength := this.proxy.Base64LineLength
+		terminator := this.proxy.Base64LineTerminator
+		if terminator == "" {
+			terminator = "\n"
+		}
+		return func(b []byte) string {
+			return wrapLines(encoding.encode(b), lineLength, terminator)
+		}
+	}
+	return encoding.encode
+}
+
+func (this *textFormatter) encodingFor(eventType EventType) Encoding {
+	if this.mixedEncodings {
+		if eventType == EventRead {
+			return this.readEncoding
+		}
+		return this.writeEncoding
+	}
+	return this.proxy.Encoding()
+}
+
+func (this *textFormatter) printEvent(format string, event *Event, args ...interface{}) {
+	if format == "" {
+		return
+	}
+	this.print(event.Type, this.withLeadingVerbs(format, event), append(this.leadingArgs(event), args...)...)
+}
+
+// withErrorSuffix appends a note of err to a data event's format, keeping any
+// trailing newline at the end.
+func withErrorSuffix(format string, err error) string {
+	suffix := " (then error: " + strings.Replace(err.Error(), "%", "%%", -1) + ")"
+	if strings.HasSuffix(format, "\n") {
+		return format[:len(format)-1] + suffix + "\n"
+	}
+	return format + suffix
+}
+
+// withLeadingVerbs prepends verbs for the event's leading arguments to format
+// if this formatter's formats don't already contain them.
+func (this *textFormatter) withLeadingVerbs(format string, event *Event) string {
+	if !this.addLeadingVerbs {
+		return format
+	}
+	prefix := ""
+	if this.proxy.SequenceInText {
+		prefix += "%v "
+	}
+	if this.proxy.ReportWriteToReadLatency && event.Type == EventRead {
+		prefix += "%v"
+	}
+	return prefix + format
+}
+
+// leadingArgs returns the format arguments that precede an event's own
+// arguments.
+func (this *textFormatter) leadingArgs(event *Event) (args []interface{}) {
+	if this.proxy.SequenceInText {
+		args = append(args, event.Sequence)
+	}
+	if this.proxy.ReportWriteToReadLatency && event.Type == EventRead {
+		annotation := ""
+		if event.FollowsWrite {
+			annotation = fmt.Sprintf("(+%v since write) ", event.SinceWrite)
+		}
+		args = append(args, annotation)
+	}
+	return
+}
+
+// labelFormat returns the proxy's label and the current event's phase as a
+// format string prefix.
+func (this *textFormatter) labelFormat() string {
+	prefix := ""
+	if this.proxy.Label != "" {
+		prefix = strings.Replace(this.proxy.Label, "%", "%%", -1) + " "
+	}
+	if this.phase != PhaseNone {
+		prefix += this.phase.String() + " "
+	}
+	return prefix
+}
+
+func (this *textFormatter) print(eventType EventType, format string, args ...interface{}) {
+	color := this.colorFor(eventType)
+	format = color + withColorEnd(color, this.labelFormat()+format)
+	switch {
+	case this.writer != nil:
+		fmt.Fprintf(this.writer, format, args...)
+	case eventType == EventRead && this.readPrintf != nil:
+		this.readPrintf(format, args...)
+	case eventType == EventWrite && this.writePrintf != nil:
+		this.writePrintf(format, args...)
+	default:
+		this.printf(format, args...)
+	}
+}
+
+func writerNotifyFunc(writer io.Writer) func(string) {
+	return func(message string) { writer.Write([]byte(message)) }
 }
 
 func errFunc(format string, function func(string, error)) func(string, error) {
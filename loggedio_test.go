@@ -3,8 +3,15 @@ package loggedio
 import (
 	"bytes"
 	"fmt"
+	"io"
+	"io/ioutil"
+	"log"
 	"net"
 	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"sync"
 	"testing"
 	"time"
 )
@@ -64,6 +71,8 @@ type MockIO struct {
 	FailAfterWriteByteCount   int
 	FailAfterReadByteCount    int
 	FailNextOperations        bool
+	ReadDeadline              time.Time
+	WriteDeadline             time.Time
 }
 
 func (this *MockIO) Read(b []byte) (n int, err error) {
@@ -134,6 +143,7 @@ func (this *MockIO) SetDeadline(t time.Time) (err error) {
 
 func (this *MockIO) SetReadDeadline(t time.Time) (err error) {
 	this.SetReadDeadlineCallCount++
+	this.ReadDeadline = t
 	if this.FailNextOperations {
 		err = generateError()
 		return
@@ -143,6 +153,7 @@ func (this *MockIO) SetReadDeadline(t time.Time) (err error) {
 
 func (this *MockIO) SetWriteDeadline(t time.Time) (err error) {
 	this.SetWriteDeadlineCallCount++
+	this.WriteDeadline = t
 	if this.FailNextOperations {
 		err = generateError()
 		return
@@ -174,6 +185,136 @@ func (this *MockCloser) Close() (err error) {
 	return this.implementation.Close()
 }
 
+type MockBlockingConn struct {
+	ReadStarted chan bool
+	closed      chan bool
+}
+
+func newMockBlockingConn() *MockBlockingConn {
+	return &MockBlockingConn{
+		ReadStarted: make(chan bool, 1),
+		closed:      make(chan bool),
+	}
+}
+
+func (this *MockBlockingConn) Read(b []byte) (n int, err error) {
+	this.ReadStarted <- true
+	<-this.closed
+	err = generateError()
+	return
+}
+
+func (this *MockBlockingConn) Close() (err error) {
+	close(this.closed)
+	return
+}
+
+// SyncBuffer is a bytes.Buffer that can be accessed from multiple goroutines.
+type SyncBuffer struct {
+	mutex  sync.Mutex
+	buffer bytes.Buffer
+}
+
+func (this *SyncBuffer) Write(b []byte) (n int, err error) {
+	this.mutex.Lock()
+	defer this.mutex.Unlock()
+	return this.buffer.Write(b)
+}
+
+func (this *SyncBuffer) String() string {
+	this.mutex.Lock()
+	defer this.mutex.Unlock()
+	return this.buffer.String()
+}
+
+type MockClock struct {
+	mutex  sync.Mutex
+	now    time.Time
+	ticker *MockTicker
+	timers []*MockTimer
+}
+
+func newMockClock() *MockClock {
+	return &MockClock{now: time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)}
+}
+
+func (this *MockClock) Now() time.Time {
+	this.mutex.Lock()
+	defer this.mutex.Unlock()
+	return this.now
+}
+
+// Advance moves the clock forward, firing the current ticker (if any) once,
+// and then running the functions of any timers that have come due.
+func (this *MockClock) Advance(d time.Duration) {
+	this.mutex.Lock()
+	this.now = this.now.Add(d)
+	now := this.now
+	ticker := this.ticker
+	var due []*MockTimer
+	pending := this.timers[:0]
+	for _, timer := range this.timers {
+		if timer.stopped {
+			continue
+		}
+		if timer.at.After(now) {
+			pending = append(pending, timer)
+		} else {
+			timer.stopped = true
+			due = append(due, timer)
+		}
+	}
+	this.timers = pending
+	this.mutex.Unlock()
+	if ticker != nil {
+		ticker.ticks <- now
+	}
+	for _, timer := range due {
+		timer.function()
+	}
+}
+
+func (this *MockClock) AfterFunc(delay time.Duration, function func()) Timer {
+	this.mutex.Lock()
+	defer this.mutex.Unlock()
+	timer := &MockTimer{clock: this, at: this.now.Add(delay), function: function}
+	this.timers = append(this.timers, timer)
+	return timer
+}
+
+type MockTimer struct {
+	clock    *MockClock
+	at       time.Time
+	function func()
+	stopped  bool
+}
+
+func (this *MockTimer) Stop() bool {
+	this.clock.mutex.Lock()
+	defer this.clock.mutex.Unlock()
+	wasPending := !this.stopped
+	this.stopped = true
+	return wasPending
+}
+
+func (this *MockClock) NewTicker(interval time.Duration) Ticker {
+	this.mutex.Lock()
+	defer this.mutex.Unlock()
+	this.ticker = &MockTicker{ticks: make(chan time.Time)}
+	return this.ticker
+}
+
+type MockTicker struct {
+	ticks chan time.Time
+}
+
+func (this *MockTicker) C() <-chan time.Time {
+	return this.ticks
+}
+
+func (this *MockTicker) Stop() {
+}
+
 // -----------------------------------------------------------------------------
 
 func expectNumber(t *testing.T, expected, actual int) {
@@ -182,6 +323,12 @@ func expectNumber(t *testing.T, expected, actual int) {
 	}
 }
 
+func expectString(t *testing.T, expected, actual string) {
+	if expected != actual {
+		t.Errorf("Expected [%v] but got [%v]", expected, actual)
+	}
+}
+
 func expectLength(t *testing.T, data []byte, length int) {
 	if length != len(data) {
 		t.Errorf("Expected data to be length %v but got %v", len(data), length)
@@ -207,6 +354,50 @@ func expectNoError(t *testing.T, err error) {
 	}
 }
 
+func TestToHex(t *testing.T) {
+	expectString(t, "", toHex([]byte{}))
+	expectString(t, "0f", toHex([]byte{0x0f}))
+	expectString(t, "00 7f ff", toHex([]byte{0x00, 0x7f, 0xff}))
+}
+
+func TestEmptyPayloadToken(t *testing.T) {
+	buffer := &bytes.Buffer{}
+	formatter := newTextFormatter(&MockIO{}, nil, buffer, EncodingHex, "R [%v]", "W [%v]", "E [%v: %v]", "C")
+	formatter.handleEvent(&Event{Type: EventWrite, Data: []byte{}})
+	expectBufferContents(t, buffer, "W []")
+
+	buffer.Reset()
+	formatter.proxy.EmptyPayloadToken = "<empty>"
+	formatter.handleEvent(&Event{Type: EventWrite, Data: []byte{}})
+	formatter.handleEvent(&Event{Type: EventWrite, Data: []byte{1}})
+	expectBufferContents(t, buffer, "W [<empty>]W [01]")
+}
+
+func TestLoggers(t *testing.T) {
+	readBuffer := &bytes.Buffer{}
+	writeBuffer := &bytes.Buffer{}
+	otherBuffer := &bytes.Buffer{}
+	logged := StringToLoggers(&MockIO{},
+		log.New(readBuffer, "read: ", 0),
+		log.New(writeBuffer, "write: ", 0),
+		log.New(otherBuffer, "other: ", 0),
+		"R [%v]", "W [%v]", "E [%v: %v]", "C")
+
+	logged.Read(make([]byte, 3))
+	logged.Write([]byte("test"))
+	logged.Close()
+	expectBufferContents(t, readBuffer, "read: R [abc]\n")
+	expectBufferContents(t, writeBuffer, "write: W [test]\n")
+	expectBufferContents(t, otherBuffer, "other: C\n")
+
+	readBuffer.Reset()
+	logged = HexToLoggers(&MockIO{},
+		log.New(readBuffer, "", 0), log.New(writeBuffer, "", 0), log.New(otherBuffer, "", 0),
+		"R [%v]", "W [%v]", "E [%v: %v]", "C")
+	logged.Read(make([]byte, 3))
+	expectBufferContents(t, readBuffer, "R [61 62 63]\n")
+}
+
 func TestReadWriteString(t *testing.T) {
 	proxied := &MockIO{}
 	buffer := &bytes.Buffer{}
@@ -288,6 +479,75 @@ func TestClose(t *testing.T) {
 	expectNumber(t, 1, proxied.CloseCallCount)
 }
 
+func TestWarnWriteLargerThan(t *testing.T) {
+	proxied := &MockIO{}
+	buffer := &bytes.Buffer{}
+	logged := HexToWriter(proxied, buffer, "R [%v]", "", "E [%v: %v]", "C")
+	logged.WarnWriteLargerThan = 50
+
+	logged.Write(make([]byte, 50))
+	expectBufferContents(t, buffer, "")
+	n, err := logged.Write(make([]byte, 100))
+	expectNoError(t, err)
+	expectNumber(t, 100, n)
+	expectNumber(t, 150, len(proxied.WriteContents))
+	expectBufferContents(t, buffer, "LoggedIO: Warning: Write() of 100 bytes is larger than 50\n")
+}
+
+func TestCombineDataAndError(t *testing.T) {
+	buffer := &bytes.Buffer{}
+	logged := StringToWriter(&MockIO{FailAfterReadByteCount: 3}, buffer, "R [%v]\n", "W [%v]\n", "E [%v: %v]\n", "C\n")
+	logged.CombineDataAndError = true
+	_, err := logged.Read(make([]byte, 10))
+	expectError(t, err)
+	expectBufferContents(t, buffer, "R [abc] (then error: ERROR!)\n")
+	expectNumber(t, 1, int(logged.Stats().Errors))
+
+	// An error without data is still reported on its own.
+	buffer.Reset()
+	logged = StringToWriter(&MockIO{FailNextOperations: true}, buffer, "R [%v]\n", "W [%v]\n", "E [%v: %v]\n", "C\n")
+	logged.CombineDataAndError = true
+	logged.Read(make([]byte, 10))
+	expectBufferContents(t, buffer, "E [Read(): ERROR!]\n")
+}
+
+func TestErrorInsteadOfCloseOnFailure(t *testing.T) {
+	proxied := &MockIO{}
+	buffer := &bytes.Buffer{}
+	logged := StringToWriter(proxied, buffer, "R [%v]", "W [%v]", "E [%v: %v]", "C")
+	logged.ErrorInsteadOfCloseOnFailure = true
+
+	expectNoError(t, logged.Close())
+	expectBufferContents(t, buffer, "C")
+
+	buffer.Reset()
+	proxied.FailNextOperations = true
+	expectError(t, logged.Close())
+	expectBufferContents(t, buffer, "E [Close(): ERROR!]")
+}
+
+func TestOnClose(t *testing.T) {
+	proxied := &MockIO{FailNextOperations: true}
+	logged := StringToWriter(proxied, &bytes.Buffer{}, "R [%v]", "W [%v]", "E [%v: %v]", "C")
+	var calls []string
+	logged.OnClose(func(err error) {
+		expectError(t, err)
+		calls = append(calls, "first")
+	})
+	logged.OnClose(func(err error) {
+		expectError(t, err)
+		calls = append(calls, "second")
+	})
+
+	expectError(t, logged.Close())
+	expectNumber(t, 2, len(calls))
+	expectString(t, "second", calls[0])
+	expectString(t, "first", calls[1])
+
+	logged.Close()
+	expectNumber(t, 2, len(calls))
+}
+
 func TestOtherOps(t *testing.T) {
 	proxied := &MockIO{}
 	buffer := &bytes.Buffer{}
@@ -390,6 +650,398 @@ func TestFail(t *testing.T) {
 	testFail(t, buffer, HexToWriter(proxied, buffer, "R [%v]", "W [%v]", "E [%v: %v]", "C"))
 }
 
+type MockPanicWriter struct{}
+
+func (this *MockPanicWriter) Write(b []byte) (n int, err error) {
+	panic("write failed")
+}
+
+// MockEmptyWriteRejecter fails all empty writes.
+type MockEmptyWriteRejecter struct{}
+
+func (this *MockEmptyWriteRejecter) Write(b []byte) (n int, err error) {
+	if len(b) == 0 {
+		return 0, fmt.Errorf("empty write")
+	}
+	return len(b), nil
+}
+
+func TestIgnoreEmptyWrites(t *testing.T) {
+	buffer := &bytes.Buffer{}
+	logged := StringToWriter(&MockEmptyWriteRejecter{}, buffer, "R [%v]", "W [%v]", "E [%v: %v]", "C")
+
+	_, err := logged.Write(nil)
+	expectError(t, err)
+	expectBufferContents(t, buffer, "E [Write(): empty write]")
+
+	buffer.Reset()
+	logged.IgnoreEmptyWrites = true
+	_, err = logged.Write(nil)
+	expectError(t, err)
+	_, err = logged.Write([]byte{})
+	expectError(t, err)
+	expectBufferContents(t, buffer, "")
+	expectNumber(t, 1, int(logged.Stats().Errors))
+
+	_, err = logged.Write([]byte("test"))
+	expectNoError(t, err)
+	expectBufferContents(t, buffer, "W [test]")
+}
+
+func TestReportBeforeWrite(t *testing.T) {
+	proxied := &MockIO{}
+	buffer := &bytes.Buffer{}
+	logged := StringToWriter(proxied, buffer, "R [%v]", "W [%v]", "E [%v: %v]", "C")
+	logged.ReportBeforeWrite = true
+	_, err := logged.Write([]byte("test"))
+	expectNoError(t, err)
+	expectBufferContents(t, buffer, "W [test]")
+
+	buffer.Reset()
+	proxied.FailNextOperations = true
+	_, err = logged.Write([]byte("test"))
+	expectError(t, err)
+	expectBufferContents(t, buffer, "W [test]E [Write(): ERROR!]")
+
+	buffer.Reset()
+	logged = StringToWriter(&MockPanicWriter{}, buffer, "R [%v]", "W [%v]", "E [%v: %v]", "C")
+	logged.ReportBeforeWrite = true
+	assertPanics(t, func() { logged.Write([]byte("test")) })
+	expectBufferContents(t, buffer, "W [test]")
+}
+
+func TestSetFormats(t *testing.T) {
+	proxied := &MockIO{}
+	buffer := &bytes.Buffer{}
+	logged := StringToWriter(proxied, buffer, "R [%v]", "W [%v]", "E [%v: %v]", "C")
+
+	logged.Read(make([]byte, 3))
+	expectBufferContents(t, buffer, "R [abc]")
+
+	buffer.Reset()
+	logged.SetReadFormat("DEBUG R [%v]")
+	logged.SetWriteFormat("DEBUG W [%v]")
+	logged.SetErrorFormat("DEBUG E [%v: %v]")
+	logged.SetCloseMessage("DEBUG C")
+	logged.Read(make([]byte, 3))
+	logged.Write([]byte("test"))
+	proxied.FailNextOperations = true
+	logged.Write([]byte("test"))
+	proxied.FailNextOperations = false
+	logged.Close()
+	expectBufferContents(t, buffer,
+		"DEBUG R [abc]DEBUG W [test]DEBUG E [Write(): ERROR!]DEBUG C")
+
+	// Not a formatting proxy, so this does nothing.
+	generic, _ := NewWithMemorySink(proxied)
+	assertNoPanic(t, func() { generic.SetReadFormat("R [%v]") })
+}
+
+func TestDumpToFilesLazily(t *testing.T) {
+	dir := t.TempDir()
+	readFile := filepath.Join(dir, "read.bin")
+	writeFile := filepath.Join(dir, "write.bin")
+	notifyFile := filepath.Join(dir, "notify.txt")
+	logged := DumpToFilesLazily(&MockReader{implementation: &MockIO{}},
+		readFile, writeFile, notifyFile, "E [%v: %v]", "C")
+
+	logged.Read(make([]byte, 3))
+	contents, err := ioutil.ReadFile(readFile)
+	expectNoError(t, err)
+	expectString(t, "abc", string(contents))
+
+	if _, err := os.Stat(writeFile); !os.IsNotExist(err) {
+		t.Errorf("Expected write dump file to not be created, but got %v", err)
+	}
+	if _, err := os.Stat(notifyFile); !os.IsNotExist(err) {
+		t.Errorf("Expected notify dump file to not be created, but got %v", err)
+	}
+}
+
+func TestDumpToWritersPreview(t *testing.T) {
+	readBuffer := &bytes.Buffer{}
+	writeBuffer := &bytes.Buffer{}
+	notifyBuffer := &bytes.Buffer{}
+	logged := DumpToWriters(&MockIO{}, readBuffer, writeBuffer, notifyBuffer, "E [%v: %v]\n", "C\n")
+	logged.PreviewBytes = 2
+
+	logged.Write([]byte("hello"))
+	logged.Read(make([]byte, 2))
+	logged.Close()
+	expectBufferContents(t, writeBuffer, "hello")
+	expectBufferContents(t, readBuffer, "ab")
+	expectBufferContents(t, notifyBuffer, ""+
+		"write 5 bytes: 68 65…\n"+
+		"read 2 bytes: 61 62\n"+
+		"C\n")
+}
+
+func TestLocationPrefix(t *testing.T) {
+	buffer := &bytes.Buffer{}
+	logged := StringToWriter(&MockIO{FailNextOperations: true}, buffer, "R [%v]\n", "W [%v]\n", "E [%v: %v]\n", "C\n")
+	logged.LocationPrefix = "ctrl: "
+	logged.Read(make([]byte, 1))
+	logged.Close()
+	expectBufferContents(t, buffer, ""+
+		"E [ctrl: Read(): ERROR!]\n"+
+		"C\n"+
+		"E [ctrl: Close(): ERROR!]\n")
+}
+
+func TestLocationMapper(t *testing.T) {
+	buffer := &bytes.Buffer{}
+	logged := StringToWriter(&MockIO{FailNextOperations: true}, buffer, "R [%v]\n", "W [%v]\n", "E [%v: %v]\n", "C\n")
+	logged.LocationMapper = strings.ToUpper
+	logged.Read(make([]byte, 1))
+	logged.LocationPrefix = "ctrl: "
+	logged.SetReadDeadline(time.Time{})
+	expectBufferContents(t, buffer, ""+
+		"E [READ(): ERROR!]\n"+
+		"E [ctrl: SETREADDEADLINE(): ERROR!]\n")
+}
+
+func TestPeekBytes(t *testing.T) {
+	buffer := &bytes.Buffer{}
+	proxied := &MockIO{}
+	logged := StringToWriter(proxied, buffer, "R [%v]\n", "W [%v]\n", "E [%v: %v]\n", "C\n")
+	logged.PeekBytes = 2
+
+	logged.Read(make([]byte, 4))
+	logged.Read(make([]byte, 1))
+	logged.Write([]byte("test"))
+	expectBufferContents(t, buffer, "R [peek=6162 abcd]\nR [peek=61 a]\nW [test]\n")
+
+	buffer.Reset()
+	logged.MaxLogBytes = 3
+	logged.Read(make([]byte, 6))
+	expectBufferContents(t, buffer, "R [peek=6162 abc… (+3 omitted)]\n")
+
+	// Large hex payloads are streamed.
+	buffer.Reset()
+	logged.MaxLogBytes = 0
+	logged.SetEncoding(EncodingHex)
+	logged.Read(make([]byte, hexStreamingThreshold+1))
+	if !strings.HasPrefix(buffer.String(), "R [peek=6162 61 62 63") {
+		t.Errorf("Expected streamed hex to start with the peek, but got %q", buffer.String()[:30])
+	}
+}
+
+func TestDumpTransform(t *testing.T) {
+	dumped := &bytes.Buffer{}
+	proxied := &MockIO{}
+	logged := DumpToWriters(proxied, &NullWriter{}, dumped, &NullWriter{}, "", "")
+	logged.DumpTransform = func(payload []byte) []byte {
+		for i := range payload {
+			payload[i] ^= 0x20
+		}
+		return payload
+	}
+
+	original := []byte("Hello")
+	logged.Write(original)
+	expectString(t, "hELLO", dumped.String())
+	expectString(t, "Hello", string(proxied.WriteContents))
+	expectString(t, "Hello", string(original))
+}
+
+// MockByteAtATimeWriter writes each byte separately to a shared, unsynchronized
+// buffer, so that concurrent writes interleave unless they're serialized.
+type MockByteAtATimeWriter struct {
+	buffer *bytes.Buffer
+}
+
+func (this *MockByteAtATimeWriter) Write(b []byte) (n int, err error) {
+	for _, ch := range b {
+		this.buffer.WriteByte(ch)
+		runtime.Gosched()
+	}
+	return len(b), nil
+}
+
+func testSerializedDump(t *testing.T, generate func(shared *bytes.Buffer) *LoggedIOProxy) {
+	shared := &bytes.Buffer{}
+	logged := generate(shared)
+	const iterations = 100
+
+	waitGroup := sync.WaitGroup{}
+	waitGroup.Add(2)
+	go func() {
+		defer waitGroup.Done()
+		for i := 0; i < iterations; i++ {
+			logged.Read(make([]byte, 4))
+		}
+	}()
+	go func() {
+		defer waitGroup.Done()
+		for i := 0; i < iterations; i++ {
+			logged.Write([]byte("WXYZ"))
+		}
+	}()
+	waitGroup.Wait()
+
+	output := shared.String()
+	expectNumber(t, iterations*8, len(output))
+	for i := 0; i < len(output); i += 4 {
+		if chunk := output[i : i+4]; chunk != "abcd" && chunk != "WXYZ" {
+			t.Fatalf("Payload split at offset %v: %q", i, chunk)
+		}
+	}
+}
+
+func TestDumpToWritersSharedWriter(t *testing.T) {
+	testSerializedDump(t, func(shared *bytes.Buffer) *LoggedIOProxy {
+		writer := &MockByteAtATimeWriter{shared}
+		proxied := struct {
+			io.Reader
+			io.Writer
+		}{&MockIO{}, ioutil.Discard}
+		return DumpToWriters(proxied, writer, writer, &NullWriter{}, "", "")
+	})
+}
+
+func TestDumpToWritersSerializeOutput(t *testing.T) {
+	testSerializedDump(t, func(shared *bytes.Buffer) *LoggedIOProxy {
+		proxied := struct {
+			io.Reader
+			io.Writer
+		}{&MockIO{}, ioutil.Discard}
+		logged := DumpToWriters(proxied, &MockByteAtATimeWriter{shared},
+			&MockByteAtATimeWriter{shared}, &NullWriter{}, "", "")
+		logged.SerializeOutput = true
+		return logged
+	})
+}
+
+func TestReportDeadlines(t *testing.T) {
+	buffer := &bytes.Buffer{}
+	logged := StringToWriter(&MockIO{}, buffer, "R [%v]", "W [%v]", "E [%v: %v]", "C")
+	clock := newMockClock()
+	logged.SetClock(clock)
+	logged.ReportDeadlines = true
+
+	logged.SetReadDeadline(clock.Now().Add(5 * time.Second))
+	logged.SetWriteDeadline(clock.Now().Add(-1500 * time.Millisecond))
+	logged.SetDeadline(time.Time{})
+	expectBufferContents(t, buffer, ""+
+		"LoggedIO: SetReadDeadline() 2020-01-01T00:00:05Z (+5s)\n"+
+		"LoggedIO: SetWriteDeadline() 2019-12-31T23:59:58.5Z (-1.5s)\n"+
+		"LoggedIO: SetDeadline() no deadline\n")
+}
+
+func TestSetTimeouts(t *testing.T) {
+	proxied := &MockIO{}
+	buffer := &bytes.Buffer{}
+	logged := StringToWriter(proxied, buffer, "R [%v]", "W [%v]", "E [%v: %v]", "C")
+	clock := newMockClock()
+	logged.SetClock(clock)
+
+	expectNoError(t, logged.SetReadTimeout(5*time.Second))
+	expectNoError(t, logged.SetWriteTimeout(1500*time.Millisecond))
+	if !proxied.ReadDeadline.Equal(clock.Now().Add(5 * time.Second)) {
+		t.Errorf("Expected read deadline 5s from now but got %v", proxied.ReadDeadline)
+	}
+	if !proxied.WriteDeadline.Equal(clock.Now().Add(1500 * time.Millisecond)) {
+		t.Errorf("Expected write deadline 1.5s from now but got %v", proxied.WriteDeadline)
+	}
+
+	expectNoError(t, logged.SetReadTimeout(0))
+	if !proxied.ReadDeadline.IsZero() {
+		t.Errorf("Expected read deadline to be cleared but got %v", proxied.ReadDeadline)
+	}
+	expectBufferContents(t, buffer, ""+
+		"LoggedIO: SetReadTimeout() 5s\n"+
+		"LoggedIO: SetWriteTimeout() 1.5s\n"+
+		"LoggedIO: SetReadTimeout() no timeout\n")
+}
+
+// MockDeadlineRecorder records the read deadline in effect at each read.
+type MockDeadlineRecorder struct {
+	*MockIO
+	readDeadlines []time.Time
+}
+
+func (this *MockDeadlineRecorder) Read(b []byte) (n int, err error) {
+	this.readDeadlines = append(this.readDeadlines, this.ReadDeadline)
+	return this.MockIO.Read(b)
+}
+
+func TestAutoReadDeadline(t *testing.T) {
+	proxied := &MockDeadlineRecorder{MockIO: &MockIO{}}
+	buffer := &bytes.Buffer{}
+	logged := StringToWriter(proxied, buffer, "R [%v]\n", "W [%v]\n", "E [%v: %v]\n", "C\n")
+	clock := newMockClock()
+	logged.SetClock(clock)
+	start := clock.Now()
+
+	logged.AutoReadDeadline(5 * time.Second)
+	logged.Read(make([]byte, 1))
+	clock.Advance(time.Second)
+	logged.Read(make([]byte, 1))
+	logged.Write([]byte("a"))
+	logged.AutoReadDeadline(0)
+	logged.Read(make([]byte, 1))
+
+	expectNumber(t, 2, proxied.SetReadDeadlineCallCount)
+	expected := []time.Time{start.Add(5 * time.Second), start.Add(6 * time.Second), start.Add(6 * time.Second)}
+	for i, deadline := range proxied.readDeadlines {
+		if !deadline.Equal(expected[i]) {
+			t.Errorf("Read %v: Expected deadline %v but got %v", i, expected[i], deadline)
+		}
+	}
+	expectBufferContents(t, buffer, ""+
+		"LoggedIO: Auto read deadline 2020-01-01T00:00:05Z (+5s)\n"+
+		"R [a]\n"+
+		"LoggedIO: Auto read deadline 2020-01-01T00:00:06Z (+5s)\n"+
+		"R [a]\n"+
+		"W [a]\n"+
+		"R [a]\n")
+
+	buffer.Reset()
+	nonConn := StringToWriter(&MockReader{&MockIO{}}, buffer, "R [%v]\n", "W [%v]\n", "E [%v: %v]\n", "C\n")
+	nonConn.AutoReadDeadline(time.Second)
+	nonConn.Read(make([]byte, 1))
+	expectBufferContents(t, buffer, "R [a]\n")
+}
+
+// MockEOFConn is a net.Conn that returns io.EOF from every read.
+type MockEOFConn struct {
+	MockIO
+}
+
+func (this *MockEOFConn) Read(b []byte) (n int, err error) {
+	return 0, io.EOF
+}
+
+func TestReportHalfClose(t *testing.T) {
+	proxied := &MockEOFConn{}
+	buffer := &bytes.Buffer{}
+	logged := StringToWriter(proxied, buffer, "R [%v]\n", "W [%v]\n", "E [%v: %v]\n", "C\n")
+	logged.ReportHalfClose = true
+
+	if logged.IsHalfClosed() {
+		t.Errorf("Expected the proxy not to be half-closed before reading EOF")
+	}
+	_, err := logged.Read(make([]byte, 3))
+	if err != io.EOF {
+		t.Errorf("Expected io.EOF but got %v", err)
+	}
+	if !logged.IsHalfClosed() {
+		t.Errorf("Expected the proxy to be half-closed after reading EOF")
+	}
+	logged.Read(make([]byte, 3))
+	_, err = logged.Write([]byte("test"))
+	expectNoError(t, err)
+	expectString(t, "test", string(proxied.WriteContents))
+	expectBufferContents(t, buffer, "LoggedIO: half-close (peer closed read side)\nW [test]\n")
+	expectNumber(t, 0, int(logged.Stats().Errors))
+
+	// Without the option, EOF is an error as usual.
+	buffer.Reset()
+	logged = StringToWriter(&MockEOFConn{}, buffer, "R [%v]\n", "W [%v]\n", "E [%v: %v]\n", "C\n")
+	logged.Read(make([]byte, 3))
+	expectBufferContents(t, buffer, "E [Read(): EOF]\n")
+}
+
 func TestWrongInterface(t *testing.T) {
 	var intf interface{}
 	var logged *LoggedIOProxy
@@ -429,6 +1081,97 @@ func TestWrongInterface(t *testing.T) {
 	assertNoPanic(t, func() { logged.Close() })
 }
 
+func TestCloseDuringRead(t *testing.T) {
+	proxied := newMockBlockingConn()
+	locations := make(chan string, 1)
+	logged := Generic(proxied,
+		func(b []byte) {},
+		func(b []byte) {},
+		func(location string, err error) { locations <- location },
+		func() {})
+
+	readResult := make(chan error)
+	go func() {
+		_, err := logged.Read(make([]byte, 1))
+		readResult <- err
+	}()
+
+	<-proxied.ReadStarted
+	expectNoError(t, logged.Close())
+	expectError(t, <-readResult)
+
+	location := <-locations
+	if location != "Read() after close" {
+		t.Errorf("Expected location \"Read() after close\" but got \"%v\"", location)
+	}
+}
+
+type MockTimeoutError struct{}
+
+func (this MockTimeoutError) Error() string   { return "timed out" }
+func (this MockTimeoutError) Timeout() bool   { return true }
+func (this MockTimeoutError) Temporary() bool { return true }
+
+type MockTimeoutReader struct{}
+
+func (this *MockTimeoutReader) Read(b []byte) (n int, err error) {
+	return 0, MockTimeoutError{}
+}
+
+func TestTimeouts(t *testing.T) {
+	buffer := &bytes.Buffer{}
+	logged := StringToWriter(&MockTimeoutReader{}, buffer, "R [%v]", "W [%v]", "E [%v: %v]", "C")
+	_, err := logged.Read(make([]byte, 1))
+	expectError(t, err)
+	expectBufferContents(t, buffer, "E [Read() timeout: timed out]")
+
+	buffer.Reset()
+	logged.SuppressTimeouts = true
+	_, err = logged.Read(make([]byte, 1))
+	expectError(t, err)
+	expectBufferContents(t, buffer, "")
+	expectNumber(t, 1, int(logged.Stats().Errors))
+}
+
+func TestLastErrorAndIsClosed(t *testing.T) {
+	proxied := &MockIO{FailAfterReadByteCount: 2}
+	logged := StringToWriter(proxied, &NullWriter{}, "R [%v]", "W [%v]", "E [%v: %v]", "C")
+	expectNoError(t, logged.LastError())
+
+	_, err := logged.Read(make([]byte, 3))
+	expectError(t, err)
+	if logged.LastError() != err {
+		t.Errorf("Expected LastError() to return %v but got %v", err, logged.LastError())
+	}
+	if logged.IsClosed() {
+		t.Errorf("Expected proxy to not be closed")
+	}
+
+	expectNoError(t, logged.Close())
+	if !logged.IsClosed() {
+		t.Errorf("Expected proxy to be closed")
+	}
+}
+
+func TestRecoverMethodMismatch(t *testing.T) {
+	buffer := &bytes.Buffer{}
+	logged := StringToWriter(&MockReader{implementation: &MockIO{}}, buffer, "R [%v]", "W [%v]", "E [%v: %v]", "C")
+	logged.RecoverMethodMismatch = true
+
+	var err error
+	assertNoPanic(t, func() { _, err = logged.Write([]byte{1}) })
+	expectError(t, err)
+	expectBufferContents(t, buffer, "E [Write(): LoggedIO: proxied object of type *loggedio.MockReader does not implement io.Writer]")
+
+	buffer.Reset()
+	assertNoPanic(t, func() { err = logged.SetDeadline(time.Now()) })
+	expectError(t, err)
+	assertNoPanic(t, func() { logged.LocalAddr() })
+	assertNoPanic(t, func() { err = logged.Close() })
+	expectError(t, err)
+	expectNumber(t, 4, int(logged.Stats().Errors))
+}
+
 func Demonstrate() {
 	readerWriter := &bytes.Buffer{}
 	var err error
@@ -0,0 +1,116 @@
+package loggedio
+
+import (
+	"fmt"
+	"regexp"
+	"sync"
+)
+
+// PayloadMatch describes an occurrence of a pattern registered via
+// OnPayloadMatch() or OnPayloadRegexp().
+type PayloadMatch struct {
+	// The direction of the payload the match was found in.
+	Direction Direction
+	// The payload of the read or write in which the match was completed.
+	Payload []byte
+	// The matching bytes. These may begin in earlier payloads (within the
+	// sliding window) and so not be wholly contained in Payload.
+	Match []byte
+}
+
+// OnPayloadMatch registers an alert that is called whenever pattern appears in
+// the data read or written through the proxy, including when it straddles the
+// boundary between consecutive reads (or writes). See OnPayloadRegexp(). An
+// empty pattern matches nothing, and so registers no alert.
+func (this *LoggedIOProxy) OnPayloadMatch(pattern []byte, alert func(match *PayloadMatch)) {
+	if len(pattern) == 0 {
+		return
+	}
+	this.OnPayloadRegexp(regexp.MustCompile(regexp.QuoteMeta(string(pattern))), len(pattern)-1, alert)
+}
+
+// OnPayloadRegexp registers an alert that is called for every match of pattern
+// in the data read or written through the proxy. Each payload is scanned along
+// with up to window trailing bytes of the previous payloads in the same
+// direction, so that matches of up to window+1 bytes are found even when split
+// across reads (or writes). Only matches that end in the new payload are
+// reported, so no match is reported twice. Empty matches are ignored.
+//
+// Only unanchored patterns that match at most window+1 bytes are supported:
+// because the scanned bytes start partway into the window, ^ and $ don't
+// correspond to any position in the stream, and longer matches may be cut
+// short or missed. Panics if window is negative.
+//
+// Alerts are separate from normal reporting: they are called whether or not
+// the proxy is enabled, and regardless of Filter. The slices in the match are
+// only valid for the duration of the call.
+func (this *LoggedIOProxy) OnPayloadRegexp(pattern *regexp.Regexp, window int, alert func(match *PayloadMatch)) {
+	if window < 0 {
+		panic(fmt.Errorf("LoggedIO: Invalid payload match window %v", window))
+	}
+	this.payloadMatchers.add(&payloadMatcher{
+		pattern: pattern,
+		window:  window,
+		alert:   alert,
+	})
+}
+
+type payloadMatcher struct {
+	pattern *regexp.Regexp
+	window  int
+	alert   func(match *PayloadMatch)
+	tails   [2][]byte
+}
+
+type payloadMatchers struct {
+	mutex    sync.Mutex
+	matchers []*payloadMatcher
+}
+
+func (this *payloadMatchers) add(matcher *payloadMatcher) {
+	this.mutex.Lock()
+	defer this.mutex.Unlock()
+	this.matchers = append(this.matchers, matcher)
+}
+
+// feed scans b for all registered patterns, and then calls the alerts of any
+// that matched (outside of the lock, so that alerts may use the proxy).
+func (this *payloadMatchers) feed(direction Direction, b []byte) {
+	this.mutex.Lock()
+	if len(this.matchers) == 0 {
+		this.mutex.Unlock()
+		return
+	}
+	var alerts []func(match *PayloadMatch)
+	var matches []*PayloadMatch
+	for _, matcher := range this.matchers {
+		for _, match := range matcher.scan(direction, b) {
+			alerts = append(alerts, matcher.alert)
+			matches = append(matches, match)
+		}
+	}
+	this.mutex.Unlock()
+
+	for i, alert := range alerts {
+		alert(matches[i])
+	}
+}
+
+func (this *payloadMatcher) scan(direction Direction, b []byte) (matches []*PayloadMatch) {
+	tail := this.tails[direction]
+	buffer := append(append([]byte(nil), tail...), b...)
+	for _, location := range this.pattern.FindAllIndex(buffer, -1) {
+		if location[1] > len(tail) && location[1] > location[0] {
+			matches = append(matches, &PayloadMatch{
+				Direction: direction,
+				Payload:   b,
+				Match:     buffer[location[0]:location[1]],
+			})
+		}
+	}
+	if len(buffer) > this.window {
+		buffer = buffer[len(buffer)-this.window:]
+	}
+	this.tails[direction] = append(this.tails[direction][:0], buffer...)
+	return
+}
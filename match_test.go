@@ -0,0 +1,81 @@
+package loggedio
+
+import (
+	"bytes"
+	"regexp"
+	"testing"
+)
+
+func TestOnPayloadMatch(t *testing.T) {
+	buffer := &bytes.Buffer{}
+	logged := StringToLog(buffer, "", "", "", "")
+	// Alerts are independent of normal reporting.
+	logged.SetEnabled(false)
+
+	var matches []PayloadMatch
+	logged.OnPayloadMatch([]byte("ERR"), func(match *PayloadMatch) {
+		matches = append(matches, PayloadMatch{
+			Direction: match.Direction,
+			Payload:   append([]byte(nil), match.Payload...),
+			Match:     append([]byte(nil), match.Match...),
+		})
+	})
+
+	logged.Write([]byte("all good"))
+	expectNumber(t, 0, len(matches))
+	logged.Write([]byte("got ERR 5"))
+	expectNumber(t, 1, len(matches))
+	if len(matches) == 1 {
+		expectString(t, "write", matches[0].Direction.String())
+		expectString(t, "got ERR 5", string(matches[0].Payload))
+		expectString(t, "ERR", string(matches[0].Match))
+	}
+
+	// Split across two writes, and only reported once.
+	logged.Write([]byte("then E"))
+	logged.Write([]byte("RR"))
+	logged.Write([]byte(" again"))
+	expectNumber(t, 2, len(matches))
+	if len(matches) == 2 {
+		expectString(t, "RR", string(matches[1].Payload))
+		expectString(t, "ERR", string(matches[1].Match))
+	}
+
+	// Reads keep their own window, separate from writes.
+	logged.Write([]byte("E"))
+	buffer.Reset()
+	buffer.WriteString("RR")
+	logged.Read(make([]byte, 10))
+	expectNumber(t, 2, len(matches))
+}
+
+func TestOnPayloadRegexp(t *testing.T) {
+	source := bytes.NewBufferString("op=17;op=42;op=17;")
+	logged := StringToLog(source, "", "", "", "")
+
+	var matches []string
+	logged.OnPayloadRegexp(regexp.MustCompile(`op=17;`), 5, func(match *PayloadMatch) {
+		expectString(t, "read", match.Direction.String())
+		matches = append(matches, string(match.Match))
+	})
+
+	readBuffer := make([]byte, 4)
+	for i := 0; i < 5; i++ {
+		logged.Read(readBuffer)
+	}
+	expectNumber(t, 2, len(matches))
+}
+
+func TestOnPayloadMatchEmptyPattern(t *testing.T) {
+	logged := StringToLog(&bytes.Buffer{}, "", "", "", "")
+
+	alerts := 0
+	logged.OnPayloadMatch(nil, func(match *PayloadMatch) { alerts++ })
+	logged.OnPayloadRegexp(regexp.MustCompile(`x*`), 0, func(match *PayloadMatch) { alerts++ })
+	logged.Write([]byte("abc"))
+	expectNumber(t, 0, alerts)
+
+	assertPanics(t, func() {
+		logged.OnPayloadRegexp(regexp.MustCompile(`abc`), -1, func(match *PayloadMatch) {})
+	})
+}
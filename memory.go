@@ -0,0 +1,128 @@
+package loggedio
+
+import (
+	"io"
+	"sync"
+)
+
+// MemorySink retains every event reported by a proxy so that it can be
+// queried after the fact, which is more convenient in tests than asserting
+// against formatted text. Payloads are copied, so they remain valid after the
+// I/O buffers they came from are reused.
+type MemorySink struct {
+	mutex  sync.Mutex
+	events []Event
+}
+
+// Events returns a copy of all events so far, in the order they were reported.
+func (this *MemorySink) Events() []Event {
+	this.mutex.Lock()
+	defer this.mutex.Unlock()
+	return append([]Event(nil), this.events...)
+}
+
+// Reads returns the payloads of all read events so far.
+func (this *MemorySink) Reads() [][]byte {
+	return this.payloads(EventRead)
+}
+
+// Writes returns the payloads of all write events so far.
+func (this *MemorySink) Writes() [][]byte {
+	return this.payloads(EventWrite)
+}
+
+// Errors returns the errors of all error events so far.
+func (this *MemorySink) Errors() []error {
+	this.mutex.Lock()
+	defer this.mutex.Unlock()
+	var errors []error
+	for _, event := range this.events {
+		if event.Type == EventError {
+			errors = append(errors, event.Err)
+		}
+	}
+	return errors
+}
+
+func (this *MemorySink) payloads(eventType EventType) [][]byte {
+	this.mutex.Lock()
+	defer this.mutex.Unlock()
+	var payloads [][]byte
+	for _, event := range this.events {
+		if event.Type == eventType {
+			payloads = append(payloads, event.Data)
+		}
+	}
+	return payloads
+}
+
+// ReportEvent stores a copy of event, making the sink usable via AddSink().
+func (this *MemorySink) ReportEvent(event *Event) {
+	stored := *event
+	if event.Data != nil {
+		stored.Data = append([]byte(nil), event.Data...)
+	}
+	this.mutex.Lock()
+	this.events = append(this.events, stored)
+	this.mutex.Unlock()
+}
+
+// ExportFormat selects the output format of MemorySink.Export().
+type ExportFormat int
+
+const (
+	// Text with payloads as strings, as produced by StringToWriter.
+	ExportText ExportFormat = iota
+	// Text with payloads as hex, as produced by HexToWriter.
+	ExportHex
+	// JSON lines, as produced by JSONToWriter.
+	ExportJSON
+)
+
+// Export writes all events retained so far to w in the specified format (for
+// example from a signal handler or a debug endpoint). Text formats write each
+// event on its own line as "R [payload]", "W [payload]", "E [location:
+// error]", or "C". Events are numbered from 1 in each export. The first error
+// returned by w is returned.
+func (this *MemorySink) Export(w io.Writer, format ExportFormat) error {
+	writer := &errorRecordingWriter{writer: w}
+	var exporter *LoggedIOProxy
+	switch format {
+	case ExportJSON:
+		exporter = JSONToWriter(nil, writer)
+	case ExportHex:
+		exporter = HexToWriter(nil, writer, "R [%v]\n", "W [%v]\n", "E [%v: %v]\n", "C\n")
+	default:
+		exporter = StringToWriter(nil, writer, "R [%v]\n", "W [%v]\n", "E [%v: %v]\n", "C\n")
+	}
+	for _, event := range this.Events() {
+		exporter.handleEvent(&event)
+		if writer.err != nil {
+			break
+		}
+	}
+	return writer.err
+}
+
+// errorRecordingWriter remembers the first error returned by its writer, and
+// discards everything written after it.
+type errorRecordingWriter struct {
+	writer io.Writer
+	err    error
+}
+
+func (this *errorRecordingWriter) Write(b []byte) (n int, err error) {
+	if this.err != nil {
+		return 0, this.err
+	}
+	n, err = this.writer.Write(b)
+	this.err = err
+	return
+}
+
+// NewWithMemorySink creates a logged I/O proxy that stores all events in the
+// returned memory sink.
+func NewWithMemorySink(proxiedObject interface{}) (*LoggedIOProxy, *MemorySink) {
+	sink := &MemorySink{}
+	return newProxy(proxiedObject, sink.ReportEvent), sink
+}
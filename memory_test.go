@@ -0,0 +1,82 @@
+package loggedio
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+)
+
+func TestMemorySink(t *testing.T) {
+	proxy, sink := NewWithMemorySink(&MockIO{})
+
+	b := make([]byte, 3)
+	proxy.Read(b)
+	// Reusing the buffer must not affect the stored payload.
+	b[0] = 'x'
+	writeBuffer := []byte("test")
+	proxy.Write(writeBuffer)
+	writeBuffer[0] = 'x'
+	proxy.Close()
+
+	reads := sink.Reads()
+	expectNumber(t, 1, len(reads))
+	expectString(t, "abc", string(reads[0]))
+	writes := sink.Writes()
+	expectNumber(t, 1, len(writes))
+	expectString(t, "test", string(writes[0]))
+	expectNumber(t, 0, len(sink.Errors()))
+	expectNumber(t, 3, len(sink.Events()))
+	expectNumber(t, int(EventClose), int(sink.Events()[2].Type))
+}
+
+func TestMemorySinkErrors(t *testing.T) {
+	proxy, sink := NewWithMemorySink(&MockIO{FailNextOperations: true})
+
+	proxy.Write([]byte("test"))
+	errors := sink.Errors()
+	expectNumber(t, 1, len(errors))
+	expectString(t, generateError().Error(), errors[0].Error())
+}
+
+func TestMemorySinkExport(t *testing.T) {
+	proxy, sink := NewWithMemorySink(&MockIO{})
+	proxy.Read(make([]byte, 3))
+	proxy.Write([]byte("test"))
+	proxy.Close()
+
+	buffer := &bytes.Buffer{}
+	expectNoError(t, sink.Export(buffer, ExportJSON))
+	type exported struct {
+		Event    string
+		Sequence uint64 `json:"seq"`
+		Data     []byte
+	}
+	var events []exported
+	decoder := json.NewDecoder(buffer)
+	for decoder.More() {
+		var event exported
+		expectNoError(t, decoder.Decode(&event))
+		events = append(events, event)
+	}
+	expectNumber(t, 3, len(events))
+	expected := []exported{{"read", 1, []byte("abc")}, {"write", 2, []byte("test")}, {"close", 3, nil}}
+	for i, event := range events {
+		if event.Event != expected[i].Event || event.Sequence != expected[i].Sequence ||
+			string(event.Data) != string(expected[i].Data) {
+			t.Errorf("Event %v: Expected %+v but got %+v", i, expected[i], event)
+		}
+	}
+
+	buffer.Reset()
+	expectNoError(t, sink.Export(buffer, ExportHex))
+	expectBufferContents(t, buffer, "R [61 62 63]\nW [74 65 73 74]\nC\n")
+	buffer.Reset()
+	expectNoError(t, sink.Export(buffer, ExportText))
+	expectBufferContents(t, buffer, "R [abc]\nW [test]\nC\n")
+}
+
+func TestMemorySinkExportError(t *testing.T) {
+	proxy, sink := NewWithMemorySink(&MockIO{})
+	proxy.Write([]byte("test"))
+	expectError(t, sink.Export(&MockWriter{&MockIO{FailNextOperations: true}}, ExportText))
+}
@@ -0,0 +1,62 @@
+package loggedio
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+)
+
+// The tags that identify the direction of each frame sent to a mirror.
+const (
+	// Data read from the proxied object.
+	MirrorTagInbound byte = 'I'
+	// Data written to the proxied object.
+	MirrorTagOutbound byte = 'O'
+)
+
+// MirrorTo sends a copy of every read and write payload to mirror (usually a
+// net.Conn to a local analyzer). Each payload is sent as a frame consisting of
+// a one byte direction tag (MirrorTagInbound or MirrorTagOutbound), the payload
+// length as a 4 byte big endian integer, and then the payload itself.
+//
+// A failure to write to the mirror doesn't affect the proxied object or its
+// error accounting (Stats(), LastError(), StopLoggingAfterError). It's
+// reported as a notification such as "LoggedIO: mirror failed: <error>", it's
+// returned by MirrorError(), and the mirror is then disabled. Passing nil
+// stops mirroring.
+func (this *LoggedIOProxy) MirrorTo(mirror io.Writer) {
+	this.mirrorMutex.Lock()
+	defer this.mirrorMutex.Unlock()
+	this.mirror = mirror
+	this.mirrorErr = nil
+}
+
+// MirrorError returns the error that disabled the current mirror (see
+// MirrorTo()), or nil if it hasn't failed.
+func (this *LoggedIOProxy) MirrorError() error {
+	this.mirrorMutex.Lock()
+	defer this.mirrorMutex.Unlock()
+	return this.mirrorErr
+}
+
+func (this *LoggedIOProxy) mirrorPayload(tag byte, b []byte) {
+	this.mirrorMutex.Lock()
+	if this.mirror == nil {
+		this.mirrorMutex.Unlock()
+		return
+	}
+	frame, pooled := this.getBuffer(5 + len(b))
+	frame = append(frame, tag, 0, 0, 0, 0)
+	binary.BigEndian.PutUint32(frame[1:], uint32(len(b)))
+	frame = append(frame, b...)
+	_, err := this.mirror.Write(frame)
+	this.putBuffer(frame, pooled)
+	if err != nil {
+		this.mirror = nil
+		this.mirrorErr = err
+	}
+	this.mirrorMutex.Unlock()
+	if err != nil {
+		this.reportNotify(fmt.Sprintf("LoggedIO: mirror failed: %v\n", err))
+	}
+}
@@ -0,0 +1,45 @@
+package loggedio
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestMirrorTo(t *testing.T) {
+	mirror := &bytes.Buffer{}
+	logged := StringToWriter(&MockIO{}, &bytes.Buffer{}, "R [%v]", "W [%v]", "E [%v: %v]", "C")
+	logged.MirrorTo(mirror)
+
+	logged.Write([]byte("hello"))
+	logged.Read(make([]byte, 2))
+	expectBufferContents(t, mirror, ""+
+		"O\x00\x00\x00\x05hello"+
+		"I\x00\x00\x00\x02ab")
+
+	mirror.Reset()
+	logged.MirrorTo(nil)
+	logged.Write([]byte("hello"))
+	expectBufferContents(t, mirror, "")
+}
+
+func TestMirrorFailure(t *testing.T) {
+	buffer := &bytes.Buffer{}
+	logged := StringToWriter(&MockIO{}, buffer, "R [%v]", "W [%v]", "E [%v: %v]", "C")
+	logged.MirrorTo(&MockWriter{implementation: &MockIO{FailNextOperations: true}})
+
+	logged.StopLoggingAfterError = true
+
+	n, err := logged.Write([]byte("hello"))
+	expectNoError(t, err)
+	expectNumber(t, 5, n)
+	logged.Write([]byte("again"))
+	expectBufferContents(t, buffer, "W [hello]LoggedIO: mirror failed: ERROR!\nW [again]")
+	expectError(t, logged.MirrorError())
+	expectNumber(t, 0, int(logged.Stats().Errors))
+	if logged.LastError() != nil {
+		t.Errorf("Expected no last error but got %v", logged.LastError())
+	}
+
+	logged.MirrorTo(&bytes.Buffer{})
+	expectNoError(t, logged.MirrorError())
+}
@@ -0,0 +1,83 @@
+package loggedio
+
+import (
+	"encoding/binary"
+	"io"
+	"sync"
+)
+
+const (
+	pcapMagic = 0xa1b2c3d4
+	// The largest packet a pcap reader is told to expect. Longer payloads are
+	// split across multiple packets.
+	pcapSnapLength = 65535
+	// LINKTYPE_USER0, reserved for private use. Each packet starts with a
+	// direction tag byte, followed by the payload.
+	pcapLinkType = 147
+)
+
+// DumpToPcap creates a logged I/O proxy that writes reads and writes to writer
+// in the classic pcap capture file format, for analysis in tools such as
+// Wireshark. The pcap header is written immediately.
+//
+// Each payload is written as one or more packets (payloads larger than 64k are
+// split) with the link type LINKTYPE_USER0 (147). Each packet's data starts
+// with a one byte direction tag (MirrorTagInbound for reads or
+// MirrorTagOutbound for writes), followed by the payload. Timestamps come from
+// the proxy's clock. Errors, closes, and notifications aren't recorded. If
+// the writer is replaced via SetTarget(), the pcap header is written to the
+// new writer first, so that it holds a complete capture file.
+func DumpToPcap(proxiedObject interface{}, writer io.Writer) *LoggedIOProxy {
+	target := newReportTarget(writer)
+	var mutex sync.Mutex
+	var this *LoggedIOProxy
+
+	writePackets := func(tag byte, b []byte) {
+		now := this.clock.Now()
+		for {
+			chunk := b
+			if len(chunk) > pcapSnapLength-1 {
+				chunk = chunk[:pcapSnapLength-1]
+			}
+			record, pooled := this.getBuffer(17 + len(chunk))
+			record = append(record, make([]byte, 17)...)
+			binary.LittleEndian.PutUint32(record[0:], uint32(now.Unix()))
+			binary.LittleEndian.PutUint32(record[4:], uint32(now.Nanosecond()/1000))
+			binary.LittleEndian.PutUint32(record[8:], uint32(len(chunk)+1))
+			binary.LittleEndian.PutUint32(record[12:], uint32(len(chunk)+1))
+			record[16] = tag
+			record = append(record, chunk...)
+			_, err := target.Write(record)
+			this.putBuffer(record, pooled)
+			if err != nil {
+				return
+			}
+			b = b[len(chunk):]
+			if len(b) == 0 {
+				return
+			}
+		}
+	}
+
+	this = newProxy(proxiedObject, func(event *Event) {
+		mutex.Lock()
+		defer mutex.Unlock()
+		switch event.Type {
+		case EventRead:
+			writePackets(MirrorTagInbound, event.Data)
+		case EventWrite:
+			writePackets(MirrorTagOutbound, event.Data)
+		}
+	})
+	this.target = target
+
+	header := make([]byte, 24)
+	binary.LittleEndian.PutUint32(header[0:], pcapMagic)
+	binary.LittleEndian.PutUint16(header[4:], 2)
+	binary.LittleEndian.PutUint16(header[6:], 4)
+	binary.LittleEndian.PutUint32(header[16:], pcapSnapLength)
+	binary.LittleEndian.PutUint32(header[20:], pcapLinkType)
+	target.header = header
+	target.Write(header)
+	return this
+}
@@ -0,0 +1,50 @@
+package loggedio
+
+import (
+	"bytes"
+	"encoding/binary"
+	"testing"
+	"time"
+)
+
+func TestDumpToPcap(t *testing.T) {
+	buffer := &bytes.Buffer{}
+	logged := DumpToPcap(&MockIO{}, buffer)
+	clock := newMockClock()
+	clock.Advance(1500 * time.Millisecond)
+	logged.SetClock(clock)
+
+	logged.Write([]byte("hello"))
+	logged.Read(make([]byte, 3))
+	logged.Close()
+
+	data := buffer.Bytes()
+	expectNumber(t, 24+(16+6)+(16+4), len(data))
+	expectNumber(t, 0xa1b2c3d4, int(binary.LittleEndian.Uint32(data[0:])))
+	expectNumber(t, 2, int(binary.LittleEndian.Uint16(data[4:])))
+	expectNumber(t, 4, int(binary.LittleEndian.Uint16(data[6:])))
+	expectNumber(t, 147, int(binary.LittleEndian.Uint32(data[20:])))
+
+	record := data[24:]
+	expectNumber(t, int(clock.Now().Unix()), int(binary.LittleEndian.Uint32(record[0:])))
+	expectNumber(t, 500000, int(binary.LittleEndian.Uint32(record[4:])))
+	expectNumber(t, 6, int(binary.LittleEndian.Uint32(record[8:])))
+	expectNumber(t, 6, int(binary.LittleEndian.Uint32(record[12:])))
+	expectString(t, "Ohello", string(record[16:22]))
+
+	record = record[22:]
+	expectNumber(t, 4, int(binary.LittleEndian.Uint32(record[8:])))
+	expectString(t, "Iabc", string(record[16:20]))
+}
+
+func TestDumpToPcapSplitsLargePayloads(t *testing.T) {
+	buffer := &bytes.Buffer{}
+	logged := DumpToPcap(&MockIO{}, buffer)
+	logged.Write(make([]byte, 70000))
+
+	record := buffer.Bytes()[24:]
+	expectNumber(t, 65535, int(binary.LittleEndian.Uint32(record[8:])))
+	record = record[16+65535:]
+	expectNumber(t, 70000-65534+1, int(binary.LittleEndian.Uint32(record[8:])))
+	expectNumber(t, 16+70000-65534+1, len(record))
+}
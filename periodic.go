@@ -0,0 +1,65 @@
+package loggedio
+
+import (
+	"sync"
+)
+
+// periodicTask runs a function on every tick of a ticker in the background,
+// from when it's started until it's stopped. It can be started and stopped
+// only once.
+type periodicTask struct {
+	startOnce sync.Once
+	stopOnce  sync.Once
+	mutex     sync.Mutex
+	stop      chan bool
+}
+
+func (this *periodicTask) start(newTicker func() Ticker, onTick func()) {
+	this.startOnce.Do(func() {
+		stop := make(chan bool)
+		this.mutex.Lock()
+		this.stop = stop
+		this.mutex.Unlock()
+		ticker := newTicker()
+		go func() {
+			defer ticker.Stop()
+			for {
+				select {
+				case <-stop:
+					return
+				case <-ticker.C():
+					onTick()
+				}
+			}
+		}()
+	})
+}
+
+func (this *periodicTask) halt() {
+	this.stopOnce.Do(func() {
+		// Prevent a later start.
+		this.startOnce.Do(func() {})
+		this.mutex.Lock()
+		stop := this.stop
+		this.mutex.Unlock()
+		if stop != nil {
+			close(stop)
+		}
+	})
+}
+
+// startBackgroundTasks starts the periodic reporting options that are set.
+// It's called on every Read() and Write(), but each task is only started once.
+func (this *LoggedIOProxy) startBackgroundTasks() {
+	if this.IdleMarkerInterval > 0 {
+		this.idle.start(func() Ticker { return this.newTicker(this.IdleMarkerInterval) }, this.checkIdle)
+	}
+	if this.ReportCallStatsEvery > 0 {
+		this.callStats.start(func() Ticker { return this.newTicker(this.ReportCallStatsEvery) }, this.reportCallStats)
+	}
+}
+
+func (this *LoggedIOProxy) stopBackgroundTasks() {
+	this.idle.halt()
+	this.callStats.halt()
+}
@@ -0,0 +1,59 @@
+package loggedio
+
+import (
+	"sync/atomic"
+)
+
+// Phase is the heuristic phase of a connection's life that an event occurred
+// in. See the HandshakeBytes and HandshakeDuration options.
+type Phase int
+
+const (
+	// Phases aren't being tracked.
+	PhaseNone Phase = iota
+	// The start of the connection, where protocols typically negotiate.
+	PhaseHandshake
+	// Everything between the handshake and the teardown.
+	PhaseSteady
+	// After the peer finished sending (Read() returned io.EOF) or the proxy
+	// was closed.
+	PhaseTeardown
+)
+
+var phaseNames = []string{
+	PhaseNone:      "none",
+	PhaseHandshake: "handshake",
+	PhaseSteady:    "steady",
+	PhaseTeardown:  "teardown",
+}
+
+func (this Phase) String() string {
+	if this >= 0 && int(this) < len(phaseNames) {
+		return phaseNames[this]
+	}
+	return "unknown"
+}
+
+// phaseOf returns the phase that event occurred in. It must be called with the
+// proxy's mutex held.
+func (this *LoggedIOProxy) phaseOf(event *Event) Phase {
+	if this.HandshakeBytes <= 0 && this.HandshakeDuration <= 0 {
+		return PhaseNone
+	}
+	if atomic.LoadInt32(&this.sawReadEOF) != 0 || this.IsClosed() {
+		return PhaseTeardown
+	}
+	// Read and write events have already been counted, but belong to the
+	// handshake if they start within it.
+	transferred := this.stats.BytesRead + this.stats.BytesWritten
+	if event.Type == EventRead || event.Type == EventWrite {
+		transferred -= int64(len(event.Data))
+	}
+	if this.HandshakeBytes > 0 && transferred >= this.HandshakeBytes {
+		return PhaseSteady
+	}
+	if this.HandshakeDuration > 0 && this.clock.Now().Sub(this.openedAt) >= this.HandshakeDuration {
+		return PhaseSteady
+	}
+	return PhaseHandshake
+}
@@ -0,0 +1,59 @@
+package loggedio
+
+import (
+	"bytes"
+	"testing"
+	"time"
+)
+
+func TestPhaseByBytes(t *testing.T) {
+	buffer := &bytes.Buffer{}
+	proxied := &MockIO{}
+	logged := StringToWriter(proxied, buffer, "R [%v]\n", "W [%v]\n", "E [%v: %v]\n", "C\n")
+	logged.HandshakeBytes = 5
+
+	logged.Write([]byte("hell"))
+	logged.Read(make([]byte, 2))
+	logged.Write([]byte("x"))
+	logged.Close()
+	expectBufferContents(t, buffer,
+		"handshake W [hell]\nhandshake R [ab]\nsteady W [x]\nteardown C\n")
+}
+
+func TestPhaseByDuration(t *testing.T) {
+	buffer := &bytes.Buffer{}
+	clock := newMockClock()
+	logged := JSONToWriter(&MockIO{}, buffer)
+	logged.SetClock(clock)
+	logged.HandshakeDuration = time.Second
+
+	logged.Write([]byte("a"))
+	clock.Advance(time.Second)
+	logged.Write([]byte("b"))
+	expectBufferContents(t, buffer,
+		`{"phase":"handshake","event":"write","seq":1,"data":"YQ=="}`+"\n"+
+			`{"phase":"steady","event":"write","seq":2,"data":"Yg=="}`+"\n")
+}
+
+func TestPhaseTeardownAfterEOF(t *testing.T) {
+	logged, sink := NewWithMemorySink(bytes.NewReader([]byte("abc")))
+	logged.HandshakeBytes = 100
+	logged.Read(make([]byte, 10))
+	logged.Read(make([]byte, 10))
+
+	var phases []Phase
+	for _, event := range sink.Events() {
+		phases = append(phases, event.Phase)
+	}
+	if len(phases) != 2 || phases[0] != PhaseHandshake || phases[1] != PhaseTeardown {
+		t.Errorf("Expected phases [handshake teardown] but got %v", phases)
+	}
+}
+
+func TestPhaseNotTracked(t *testing.T) {
+	buffer := &bytes.Buffer{}
+	logged := StringToWriter(&MockIO{}, buffer, "R [%v]\n", "W [%v]\n", "E [%v: %v]\n", "C\n")
+	logged.Write([]byte("test"))
+	expectBufferContents(t, buffer, "W [test]\n")
+	expectString(t, "none", PhaseNone.String())
+}
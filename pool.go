@@ -0,0 +1,39 @@
+package loggedio
+
+import (
+	"sync"
+)
+
+// getBuffer returns a zero length buffer with at least the given capacity. If
+// BufferPool is set, the buffer is taken from it, and pooled is the pool entry
+// to hand back via putBuffer() once the buffer is no longer referenced.
+// Otherwise pooled is nil.
+func (this *LoggedIOProxy) getBuffer(capacity int) (buffer []byte, pooled *[]byte) {
+	if this.BufferPool == nil {
+		return make([]byte, 0, capacity), nil
+	}
+	pooled, _ = this.BufferPool.Get().(*[]byte)
+	if pooled == nil {
+		pooled = new([]byte)
+	}
+	if cap(*pooled) < capacity {
+		*pooled = make([]byte, 0, capacity)
+	}
+	return (*pooled)[:0], pooled
+}
+
+// putBuffer returns buffer (which may have grown since getBuffer()) to the
+// pool that pooled came from.
+func (this *LoggedIOProxy) putBuffer(buffer []byte, pooled *[]byte) {
+	if pooled == nil {
+		return
+	}
+	*pooled = buffer
+	this.BufferPool.Put(pooled)
+}
+
+// NewBufferPool returns a pool suitable for LoggedIOProxy.BufferPool. It can
+// be shared by any number of proxies.
+func NewBufferPool() *sync.Pool {
+	return &sync.Pool{}
+}
@@ -0,0 +1,61 @@
+package loggedio
+
+import (
+	"bytes"
+	"io/ioutil"
+	"testing"
+)
+
+func TestBufferPoolMirror(t *testing.T) {
+	mirror := &bytes.Buffer{}
+	logged := StringToWriter(&MockIO{}, &NullWriter{}, "R [%v]", "W [%v]", "E [%v: %v]", "C")
+	logged.BufferPool = NewBufferPool()
+	logged.MirrorTo(mirror)
+
+	logged.Write([]byte("a longer payload"))
+	logged.Write([]byte("short"))
+	logged.Read(make([]byte, 2))
+	expectBufferContents(t, mirror, ""+
+		"O\x00\x00\x00\x10a longer payload"+
+		"O\x00\x00\x00\x05short"+
+		"I\x00\x00\x00\x02ab")
+}
+
+func TestBufferPoolPcap(t *testing.T) {
+	pooled := &bytes.Buffer{}
+	logged := DumpToPcap(&MockIO{}, pooled)
+	logged.BufferPool = NewBufferPool()
+	unpooled := &bytes.Buffer{}
+	reference := DumpToPcap(&MockIO{}, unpooled)
+	clock := newMockClock()
+	logged.SetClock(clock)
+	reference.SetClock(clock)
+
+	for _, payload := range []string{"a longer payload", "short", "x"} {
+		logged.Write([]byte(payload))
+		reference.Write([]byte(payload))
+	}
+	expectString(t, unpooled.String(), pooled.String())
+}
+
+func benchmarkMirror(b *testing.B, usePool bool) {
+	logged := StringToWriter(ioutil.Discard, ioutil.Discard, "", "", "", "")
+	if usePool {
+		logged.BufferPool = NewBufferPool()
+	}
+	logged.MirrorTo(ioutil.Discard)
+	payload := make([]byte, 4096)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		logged.Write(payload)
+	}
+}
+
+func BenchmarkMirrorUnpooled(b *testing.B) {
+	benchmarkMirror(b, false)
+}
+
+func BenchmarkMirrorPooled(b *testing.B) {
+	benchmarkMirror(b, true)
+}
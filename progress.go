@@ -0,0 +1,29 @@
+package loggedio
+
+import (
+	"fmt"
+)
+
+// SetProgressCallback sets the function that's called each time the total
+// bytes transferred crosses a multiple of the proxy's ProgressEvery option,
+// receiving that multiple. This overrides the default behavior of reporting a
+// "LoggedIO: Progress: <n> bytes transferred" notification.
+func (this *LoggedIOProxy) SetProgressCallback(reportProgress func(transferred int64)) {
+	this.reportProgress = reportProgress
+}
+
+// progress reports every multiple of ProgressEvery that lies in the range
+// (before, after].
+func (this *LoggedIOProxy) progress(before, after int64) {
+	every := this.ProgressEvery
+	if every <= 0 {
+		return
+	}
+	for boundary := (before/every + 1) * every; boundary <= after; boundary += every {
+		if this.reportProgress != nil {
+			this.reportProgress(boundary)
+		} else {
+			this.reportNotify(fmt.Sprintf("LoggedIO: Progress: %v bytes transferred\n", boundary))
+		}
+	}
+}
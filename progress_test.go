@@ -0,0 +1,33 @@
+package loggedio
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestProgressEvery(t *testing.T) {
+	logged := StringToWriter(&MockIO{}, &bytes.Buffer{}, "R [%v]", "W [%v]", "E [%v: %v]", "C")
+	logged.ProgressEvery = 10
+	var progress []int64
+	logged.SetProgressCallback(func(transferred int64) {
+		progress = append(progress, transferred)
+	})
+
+	logged.Write(make([]byte, 7))
+	expectNumber(t, 0, len(progress))
+	logged.Read(make([]byte, 5))
+	expectNumber(t, 1, len(progress))
+	logged.Write(make([]byte, 30))
+	expectNumber(t, 4, len(progress))
+	for i, expected := range []int64{10, 20, 30, 40} {
+		expectNumber(t, int(expected), int(progress[i]))
+	}
+}
+
+func TestProgressNotification(t *testing.T) {
+	buffer := &bytes.Buffer{}
+	logged := StringToWriter(&MockIO{}, buffer, "", "", "E [%v: %v]", "C")
+	logged.ProgressEvery = 4
+	logged.Write(make([]byte, 5))
+	expectBufferContents(t, buffer, "LoggedIO: Progress: 4 bytes transferred\n")
+}
@@ -0,0 +1,226 @@
+// Package protolog records a logged I/O proxy's events as protobuf messages,
+// for log pipelines built around protobuf.
+//
+// To keep the core loggedio package (and this one) free of protobuf
+// dependencies, messages are encoded and decoded by hand. They're wire
+// compatible with this definition, so other languages can generate decoders
+// from it:
+//
+//	syntax = "proto3";
+//
+//	message Event {
+//	    enum Type {
+//	        READ = 0;
+//	        WRITE = 1;
+//	        ERROR = 2;
+//	        CLOSE = 3;
+//	        NOTIFY = 4;
+//	    }
+//	    Type type = 1;
+//	    bytes data = 2;
+//	    string location = 3;
+//	    string error = 4;
+//	    string message = 5;
+//	    uint64 completion = 6;
+//	    string label = 7;
+//	    string phase = 8;
+//	}
+//
+// Each message in a stream is preceded by its length as a varint, as written
+// by Java's writeDelimitedTo() and C++'s SerializeDelimitedToOstream().
+package protolog
+
+import (
+	"bufio"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"sync"
+
+	"github.com/kstenerud/go-loggedio"
+)
+
+// Event is the Go equivalent of the Event message.
+type Event struct {
+	Type       loggedio.EventType
+	Data       []byte
+	Location   string
+	Error      string
+	Message    string
+	Completion uint64
+	Label      string
+	Phase      string
+}
+
+const (
+	fieldType       = 1
+	fieldData       = 2
+	fieldLocation   = 3
+	fieldError      = 4
+	fieldMessage    = 5
+	fieldCompletion = 6
+	fieldLabel      = 7
+	fieldPhase      = 8
+)
+
+const (
+	wireVarint  = 0
+	wireFixed64 = 1
+	wireBytes   = 2
+	wireFixed32 = 5
+)
+
+// FromEvent converts a proxy's event to a message, labeled with label.
+func FromEvent(event *loggedio.Event, label string) *Event {
+	this := &Event{
+		Type:       event.Type,
+		Data:       event.Data,
+		Location:   event.Location,
+		Message:    event.Message,
+		Completion: event.Completion,
+		Label:      label,
+	}
+	if event.Err != nil {
+		this.Error = event.Err.Error()
+	}
+	if event.Phase != loggedio.PhaseNone {
+		this.Phase = event.Phase.String()
+	}
+	return this
+}
+
+// Marshal encodes the event as a protobuf message. As in proto3, fields with
+// zero values are omitted.
+func (this *Event) Marshal() []byte {
+	var b []byte
+	if this.Type != 0 {
+		b = appendVarintField(b, fieldType, uint64(this.Type))
+	}
+	b = appendBytesField(b, fieldData, this.Data)
+	b = appendBytesField(b, fieldLocation, []byte(this.Location))
+	b = appendBytesField(b, fieldError, []byte(this.Error))
+	b = appendBytesField(b, fieldMessage, []byte(this.Message))
+	if this.Completion != 0 {
+		b = appendVarintField(b, fieldCompletion, this.Completion)
+	}
+	b = appendBytesField(b, fieldLabel, []byte(this.Label))
+	b = appendBytesField(b, fieldPhase, []byte(this.Phase))
+	return b
+}
+
+func appendUvarint(b []byte, value uint64) []byte {
+	var buffer [binary.MaxVarintLen64]byte
+	return append(b, buffer[:binary.PutUvarint(buffer[:], value)]...)
+}
+
+func appendVarintField(b []byte, field int, value uint64) []byte {
+	b = appendUvarint(b, uint64(field<<3|wireVarint))
+	return appendUvarint(b, value)
+}
+
+func appendBytesField(b []byte, field int, value []byte) []byte {
+	if len(value) == 0 {
+		return b
+	}
+	b = appendUvarint(b, uint64(field<<3|wireBytes))
+	b = appendUvarint(b, uint64(len(value)))
+	return append(b, value...)
+}
+
+// Unmarshal decodes a protobuf message. Unknown fields are skipped.
+func Unmarshal(b []byte) (*Event, error) {
+	this := &Event{}
+	for len(b) > 0 {
+		key, n := binary.Uvarint(b)
+		if n <= 0 {
+			return nil, fmt.Errorf("protolog: invalid field key")
+		}
+		b = b[n:]
+		field, wireType := int(key>>3), int(key&7)
+		switch wireType {
+		case wireVarint:
+			value, n := binary.Uvarint(b)
+			if n <= 0 {
+				return nil, fmt.Errorf("protolog: invalid varint in field %v", field)
+			}
+			b = b[n:]
+			switch field {
+			case fieldType:
+				this.Type = loggedio.EventType(value)
+			case fieldCompletion:
+				this.Completion = value
+			}
+		case wireBytes:
+			length, n := binary.Uvarint(b)
+			if n <= 0 || length > uint64(len(b)-n) {
+				return nil, fmt.Errorf("protolog: invalid length in field %v", field)
+			}
+			value := b[n : n+int(length)]
+			b = b[n+int(length):]
+			switch field {
+			case fieldData:
+				this.Data = append([]byte(nil), value...)
+			case fieldLocation:
+				this.Location = string(value)
+			case fieldError:
+				this.Error = string(value)
+			case fieldMessage:
+				this.Message = string(value)
+			case fieldLabel:
+				this.Label = string(value)
+			case fieldPhase:
+				this.Phase = string(value)
+			}
+		case wireFixed64, wireFixed32:
+			size := 8
+			if wireType == wireFixed32 {
+				size = 4
+			}
+			if len(b) < size {
+				return nil, fmt.Errorf("protolog: truncated field %v", field)
+			}
+			b = b[size:]
+		default:
+			return nil, fmt.Errorf("protolog: unsupported wire type %v in field %v", wireType, field)
+		}
+	}
+	return this, nil
+}
+
+// WriteDelimited writes the event to w as a length-delimited message.
+func WriteDelimited(w io.Writer, event *Event) error {
+	message := event.Marshal()
+	b := appendUvarint(make([]byte, 0, len(message)+binary.MaxVarintLen64), uint64(len(message)))
+	_, err := w.Write(append(b, message...))
+	return err
+}
+
+// ReadDelimited reads the next length-delimited message from r. It returns
+// io.EOF if there are no more messages.
+func ReadDelimited(r *bufio.Reader) (*Event, error) {
+	length, err := binary.ReadUvarint(r)
+	if err != nil {
+		return nil, err
+	}
+	message := make([]byte, length)
+	if _, err := io.ReadFull(r, message); err != nil {
+		if err == io.EOF {
+			err = io.ErrUnexpectedEOF
+		}
+		return nil, err
+	}
+	return Unmarshal(message)
+}
+
+// Record writes each of the proxy's subsequent events to w as a
+// length-delimited message, labeled with the proxy's Label. Write errors are
+// ignored. The proxy's existing reporting is unaffected.
+func Record(proxy *loggedio.LoggedIOProxy, w io.Writer) {
+	mutex := sync.Mutex{}
+	proxy.AddSink(loggedio.SinkFunc(func(event *loggedio.Event) {
+		message := FromEvent(event, proxy.Label)
+		mutex.Lock()
+		defer mutex.Unlock()
+		WriteDelimited(w, message)
+	}))
+}
@@ -0,0 +1,103 @@
+package protolog
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"testing"
+
+	"github.com/kstenerud/go-loggedio"
+)
+
+type MockIO struct {
+	bytes.Buffer
+}
+
+func (this *MockIO) Close() error {
+	return fmt.Errorf("close failed")
+}
+
+func expectEvent(t *testing.T, expected, actual *Event) {
+	if fmt.Sprintf("%+v", expected) != fmt.Sprintf("%+v", actual) {
+		t.Errorf("Expected %+v but got %+v", expected, actual)
+	}
+}
+
+func TestRoundTrip(t *testing.T) {
+	event := &Event{
+		Type:       loggedio.EventWrite,
+		Data:       []byte("test"),
+		Completion: 300,
+		Label:      "conn1",
+		Phase:      "handshake",
+	}
+	decoded, err := Unmarshal(event.Marshal())
+	if err != nil {
+		t.Fatal(err)
+	}
+	expectEvent(t, event, decoded)
+
+	// Zero values are omitted, as in proto3.
+	if encoded := (&Event{}).Marshal(); len(encoded) != 0 {
+		t.Errorf("Expected an empty message but got %x", encoded)
+	}
+}
+
+func TestWireFormat(t *testing.T) {
+	encoded := (&Event{Type: loggedio.EventWrite, Data: []byte("hi"), Completion: 150}).Marshal()
+	expected := []byte{0x08, 0x01, 0x12, 0x02, 'h', 'i', 0x30, 0x96, 0x01}
+	if !bytes.Equal(expected, encoded) {
+		t.Errorf("Expected %x but got %x", expected, encoded)
+	}
+}
+
+func TestUnmarshalSkipsUnknownFields(t *testing.T) {
+	encoded := []byte{
+		0x08, 0x02, // type = ERROR
+		0x48, 0x05, // field 9 varint
+		0x51, 1, 2, 3, 4, 5, 6, 7, 8, // field 10 fixed64
+		0x5a, 0x01, 'x', // field 11 bytes
+		0x65, 1, 2, 3, 4, // field 12 fixed32
+		0x1a, 0x06, 'R', 'e', 'a', 'd', '(', ')', // location
+	}
+	decoded, err := Unmarshal(encoded)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expectEvent(t, &Event{Type: loggedio.EventError, Location: "Read()"}, decoded)
+
+	if _, err := Unmarshal([]byte{0x12, 0x05, 'a'}); err == nil {
+		t.Errorf("Expected an error for a truncated message")
+	}
+}
+
+func TestRecord(t *testing.T) {
+	proxied := &MockIO{}
+	proxy := loggedio.StringToWriter(proxied, io.Discard, "R [%v]", "W [%v]", "E [%v: %v]", "C")
+	proxy.Label = "conn1"
+	recorded := &bytes.Buffer{}
+	Record(proxy, recorded)
+
+	proxy.Write([]byte("test"))
+	proxy.Read(make([]byte, 10))
+	proxy.Close()
+
+	reader := bufio.NewReader(recorded)
+	expected := []*Event{
+		{Type: loggedio.EventWrite, Data: []byte("test"), Label: "conn1"},
+		{Type: loggedio.EventRead, Data: []byte("test"), Label: "conn1"},
+		{Type: loggedio.EventClose, Label: "conn1"},
+		{Type: loggedio.EventError, Location: "Close()", Error: "close failed", Label: "conn1"},
+	}
+	for _, expectedEvent := range expected {
+		event, err := ReadDelimited(reader)
+		if err != nil {
+			t.Fatal(err)
+		}
+		expectEvent(t, expectedEvent, event)
+	}
+	if _, err := ReadDelimited(reader); err != io.EOF {
+		t.Errorf("Expected io.EOF but got %v", err)
+	}
+}
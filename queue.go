@@ -0,0 +1,108 @@
+package loggedio
+
+import (
+	"sync"
+)
+
+// BackpressurePolicy determines what an event queue does with a new event when
+// it's full.
+type BackpressurePolicy int
+
+const (
+	// Wait until the consumer makes room, stalling the I/O call that
+	// generated the event.
+	BackpressureBlock BackpressurePolicy = iota
+	// Discard the new event.
+	BackpressureDropNewest
+	// Discard the oldest queued event to make room for the new one. A queue
+	// with a capacity of 0 has nothing to discard, and so discards the new
+	// event instead (as BackpressureDropNewest).
+	BackpressureDropOldest
+)
+
+// QueueCounters counts the outcomes of an event queue's backpressure policy.
+type QueueCounters struct {
+	// Events that had to wait for room in the queue (BackpressureBlock).
+	Blocked uint64
+	// New events discarded because the queue was full
+	// (BackpressureDropNewest).
+	DroppedNewest uint64
+	// Queued events evicted to make room for new ones
+	// (BackpressureDropOldest).
+	DroppedOldest uint64
+}
+
+// EventQueue delivers a proxy's events asynchronously over a channel of fixed
+// capacity, applying a backpressure policy when the consumer falls behind.
+// Payloads are copied, so they remain valid after the I/O buffers they came
+// from are reused.
+type EventQueue struct {
+	mutex    sync.Mutex
+	events   chan Event
+	policy   BackpressurePolicy
+	counters QueueCounters
+}
+
+// NewWithEventQueue creates a logged I/O proxy that sends all events to the
+// returned queue, which holds up to capacity events.
+func NewWithEventQueue(proxiedObject interface{}, capacity int, policy BackpressurePolicy) (*LoggedIOProxy, *EventQueue) {
+	queue := &EventQueue{
+		events: make(chan Event, capacity),
+		policy: policy,
+	}
+	return newProxy(proxiedObject, queue.ReportEvent), queue
+}
+
+// Events returns the channel that events are delivered on. The channel is
+// never closed; a close event marks the end of the session.
+func (this *EventQueue) Events() <-chan Event {
+	return this.events
+}
+
+// Counters returns the backpressure outcome counts so far.
+func (this *EventQueue) Counters() QueueCounters {
+	this.mutex.Lock()
+	defer this.mutex.Unlock()
+	return this.counters
+}
+
+// ReportEvent queues a copy of event, making the queue usable via AddSink().
+func (this *EventQueue) ReportEvent(event *Event) {
+	queued := *event
+	if event.Data != nil {
+		queued.Data = append([]byte(nil), event.Data...)
+	}
+
+	this.mutex.Lock()
+	defer this.mutex.Unlock()
+	for {
+		select {
+		case this.events <- queued:
+			return
+		default:
+		}
+
+		policy := this.policy
+		if policy == BackpressureDropOldest && cap(this.events) == 0 {
+			policy = BackpressureDropNewest
+		}
+		switch policy {
+		case BackpressureDropNewest:
+			this.counters.DroppedNewest++
+			return
+		case BackpressureDropOldest:
+			select {
+			case <-this.events:
+				this.counters.DroppedOldest++
+			default:
+			}
+		default:
+			this.counters.Blocked++
+			// Don't hold the mutex while waiting, so that Counters() can't stall.
+			this.mutex.Unlock()
+			this.events <- queued
+			this.mutex.Lock()
+			return
+		}
+	}
+}
@@ -0,0 +1,82 @@
+package loggedio
+
+import (
+	"testing"
+	"time"
+)
+
+func writeThree(proxy *LoggedIOProxy) {
+	proxy.Write([]byte("a"))
+	proxy.Write([]byte("b"))
+	proxy.Write([]byte("c"))
+}
+
+func expectQueuedPayloads(t *testing.T, queue *EventQueue, expected ...string) {
+	for _, payload := range expected {
+		select {
+		case event := <-queue.Events():
+			expectString(t, payload, string(event.Data))
+		default:
+			t.Errorf("Expected queued payload %v but the queue was empty", payload)
+			return
+		}
+	}
+	select {
+	case event := <-queue.Events():
+		t.Errorf("Expected queue to be empty but got %v", event)
+	default:
+	}
+}
+
+func TestQueueDropNewest(t *testing.T) {
+	proxy, queue := NewWithEventQueue(&MockIO{}, 2, BackpressureDropNewest)
+	writeThree(proxy)
+	expectQueuedPayloads(t, queue, "a", "b")
+	expectNumber(t, 1, int(queue.Counters().DroppedNewest))
+}
+
+func TestQueueDropOldest(t *testing.T) {
+	proxy, queue := NewWithEventQueue(&MockIO{}, 2, BackpressureDropOldest)
+	writeThree(proxy)
+	expectQueuedPayloads(t, queue, "b", "c")
+	expectNumber(t, 1, int(queue.Counters().DroppedOldest))
+}
+
+func TestQueueDropOldestUnbuffered(t *testing.T) {
+	proxy, queue := NewWithEventQueue(&MockIO{}, 0, BackpressureDropOldest)
+	done := make(chan bool)
+	go func() {
+		writeThree(proxy)
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatalf("Expected writes to an unbuffered queue not to block")
+	}
+	expectQueuedPayloads(t, queue)
+	counters := queue.Counters()
+	expectNumber(t, 3, int(counters.DroppedNewest))
+	expectNumber(t, 0, int(counters.DroppedOldest))
+}
+
+func TestQueueBlock(t *testing.T) {
+	proxy, queue := NewWithEventQueue(&MockIO{}, 2, BackpressureBlock)
+	done := make(chan bool)
+	go func() {
+		writeThree(proxy)
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		t.Fatalf("Expected the third write to block")
+	case <-time.After(50 * time.Millisecond):
+	}
+	expectNumber(t, 1, int(queue.Counters().Blocked))
+
+	expectString(t, "a", string((<-queue.Events()).Data))
+	<-done
+	expectQueuedPayloads(t, queue, "b", "c")
+}
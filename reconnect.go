@@ -0,0 +1,149 @@
+package loggedio
+
+import (
+	"errors"
+	"io"
+	"net"
+	"sync"
+	"syscall"
+	"time"
+)
+
+// NewReconnectAware dials a connection and wraps it in a logged I/O proxy
+// built by generate. If a read or write fails with an error indicating that
+// the connection is dead (EOF, closed, reset, or broken pipe), the proxy
+// reports a "LoggedIO: Reconnecting after <location>: <error>" notification,
+// dials a new connection, and retries the operation once on it. A successful
+// reconnect is reported as a "LoggedIO: Reconnected" notification, and a
+// failed one as an error with the location "LoggedIO reconnect" (after which
+// the original error is returned).
+//
+// Because the same proxy is used across reconnects, its Stats() and sequence
+// numbers cover the whole session. Deadlines set via the proxy are reapplied
+// to new connections. No reconnect is attempted once the proxy is closed.
+func NewReconnectAware(dial func() (net.Conn, error), generate ProxyGenerator) (*LoggedIOProxy, error) {
+	conn, err := dial()
+	if err != nil {
+		return nil, err
+	}
+	reconnecting := &reconnectAware{dial: dial, conn: conn}
+	reconnecting.proxy = generate(reconnecting)
+	return reconnecting.proxy, nil
+}
+
+type reconnectAware struct {
+	mutex         sync.Mutex
+	proxy         *LoggedIOProxy
+	dial          func() (net.Conn, error)
+	conn          net.Conn
+	closed        bool
+	readDeadline  time.Time
+	writeDeadline time.Time
+}
+
+func isDeadConnection(err error) bool {
+	switch ClassifyError(err) {
+	case ErrorClassEOF, ErrorClassClosed:
+		return true
+	}
+	return errors.Is(err, io.ErrClosedPipe) ||
+		errors.Is(err, syscall.ECONNRESET) ||
+		errors.Is(err, syscall.EPIPE)
+}
+
+func (this *reconnectAware) current() net.Conn {
+	this.mutex.Lock()
+	defer this.mutex.Unlock()
+	return this.conn
+}
+
+// reconnect replaces failed with a newly dialed connection, unless another
+// operation already replaced it. It returns nil if no retry should be made.
+func (this *reconnectAware) reconnect(failed net.Conn, location string, err error) net.Conn {
+	this.mutex.Lock()
+	defer this.mutex.Unlock()
+	if this.closed {
+		return nil
+	}
+	if this.conn != failed {
+		return this.conn
+	}
+
+	this.proxy.reportNotify("LoggedIO: Reconnecting after " + location + ": " + err.Error() + "\n")
+	conn, dialErr := this.dial()
+	if dialErr != nil {
+		this.proxy.reportError("LoggedIO reconnect", dialErr)
+		return nil
+	}
+	failed.Close()
+	if !this.readDeadline.IsZero() {
+		conn.SetReadDeadline(this.readDeadline)
+	}
+	if !this.writeDeadline.IsZero() {
+		conn.SetWriteDeadline(this.writeDeadline)
+	}
+	this.conn = conn
+	this.proxy.reportNotify("LoggedIO: Reconnected\n")
+	return conn
+}
+
+func (this *reconnectAware) Read(b []byte) (n int, err error) {
+	conn := this.current()
+	n, err = conn.Read(b)
+	if n == 0 && err != nil && isDeadConnection(err) {
+		if retryConn := this.reconnect(conn, "Read()", err); retryConn != nil {
+			return retryConn.Read(b)
+		}
+	}
+	return
+}
+
+func (this *reconnectAware) Write(b []byte) (n int, err error) {
+	conn := this.current()
+	n, err = conn.Write(b)
+	if err != nil && isDeadConnection(err) {
+		if retryConn := this.reconnect(conn, "Write()", err); retryConn != nil {
+			var retried int
+			retried, err = retryConn.Write(b[n:])
+			n += retried
+		}
+	}
+	return
+}
+
+func (this *reconnectAware) Close() error {
+	this.mutex.Lock()
+	defer this.mutex.Unlock()
+	this.closed = true
+	return this.conn.Close()
+}
+
+func (this *reconnectAware) LocalAddr() net.Addr {
+	return this.current().LocalAddr()
+}
+
+func (this *reconnectAware) RemoteAddr() net.Addr {
+	return this.current().RemoteAddr()
+}
+
+func (this *reconnectAware) SetDeadline(t time.Time) error {
+	this.mutex.Lock()
+	defer this.mutex.Unlock()
+	this.readDeadline = t
+	this.writeDeadline = t
+	return this.conn.SetDeadline(t)
+}
+
+func (this *reconnectAware) SetReadDeadline(t time.Time) error {
+	this.mutex.Lock()
+	defer this.mutex.Unlock()
+	this.readDeadline = t
+	return this.conn.SetReadDeadline(t)
+}
+
+func (this *reconnectAware) SetWriteDeadline(t time.Time) error {
+	this.mutex.Lock()
+	defer this.mutex.Unlock()
+	this.writeDeadline = t
+	return this.conn.SetWriteDeadline(t)
+}
@@ -0,0 +1,77 @@
+package loggedio
+
+import (
+	"bytes"
+	"net"
+	"testing"
+)
+
+// MockDeadConn is a net.Conn whose connection has been lost.
+type MockDeadConn struct {
+	MockIO
+}
+
+func (this *MockDeadConn) Read(b []byte) (n int, err error) {
+	return 0, net.ErrClosed
+}
+
+func (this *MockDeadConn) Write(b []byte) (n int, err error) {
+	return 0, net.ErrClosed
+}
+
+func newReconnectTest(t *testing.T, conns ...net.Conn) (*LoggedIOProxy, *bytes.Buffer) {
+	buffer := &bytes.Buffer{}
+	logged, err := NewReconnectAware(func() (net.Conn, error) {
+		if len(conns) == 0 {
+			return nil, generateError()
+		}
+		conn := conns[0]
+		conns = conns[1:]
+		return conn, nil
+	}, func(o interface{}) *LoggedIOProxy {
+		return StringToWriter(o, buffer, "R [%v]", "W [%v]", "E [%v: %v]", "C")
+	})
+	expectNoError(t, err)
+	return logged, buffer
+}
+
+func TestReconnect(t *testing.T) {
+	dead := &MockDeadConn{}
+	live := &MockIO{}
+	logged, buffer := newReconnectTest(t, dead, live)
+
+	n, err := logged.Write([]byte("test"))
+	expectNoError(t, err)
+	expectNumber(t, 4, n)
+	expectString(t, "test", string(live.WriteContents))
+	expectNumber(t, 1, dead.CloseCallCount)
+	expectBufferContents(t, buffer, ""+
+		"LoggedIO: Reconnecting after Write(): use of closed network connection\n"+
+		"LoggedIO: Reconnected\n"+
+		"W [test]")
+
+	buffer.Reset()
+	logged.Read(make([]byte, 3))
+	expectBufferContents(t, buffer, "R [abc]")
+}
+
+func TestReconnectFailure(t *testing.T) {
+	logged, buffer := newReconnectTest(t, &MockDeadConn{})
+
+	_, err := logged.Read(make([]byte, 3))
+	expectError(t, err)
+	expectBufferContents(t, buffer, ""+
+		"LoggedIO: Reconnecting after Read(): use of closed network connection\n"+
+		"E [LoggedIO reconnect: ERROR!]"+
+		"E [Read(): use of closed network connection]")
+}
+
+func TestNoReconnectAfterClose(t *testing.T) {
+	logged, buffer := newReconnectTest(t, &MockDeadConn{}, &MockIO{})
+
+	logged.Close()
+	buffer.Reset()
+	_, err := logged.Write([]byte("test"))
+	expectError(t, err)
+	expectBufferContents(t, buffer, "E [Write() after close: use of closed network connection]")
+}
@@ -0,0 +1,152 @@
+package loggedio
+
+import (
+	"bytes"
+	"io"
+	"net"
+	"sync"
+	"time"
+)
+
+// ReplayRecord is a single captured read or write payload.
+type ReplayRecord struct {
+	Direction EventType // EventRead or EventWrite
+
+	// The time since the capture began, according to the proxy's clock.
+	At time.Duration
+
+	Data []byte
+}
+
+// Replay holds a captured session, in the order the payloads were reported.
+type Replay struct {
+	mutex   sync.Mutex
+	records []ReplayRecord
+}
+
+// Records returns a copy of all records captured so far.
+func (this *Replay) Records() []ReplayRecord {
+	this.mutex.Lock()
+	defer this.mutex.Unlock()
+	return append([]ReplayRecord(nil), this.records...)
+}
+
+// Overridden in tests.
+var sleep = time.Sleep
+
+// CaptureReplay creates a logged I/O proxy that captures every read and write
+// payload, along with when it happened, into the returned replay.
+func CaptureReplay(proxiedObject interface{}) (*LoggedIOProxy, *Replay) {
+	replay := &Replay{}
+	var this *LoggedIOProxy
+	this = newProxy(proxiedObject, func(event *Event) {
+		if event.Type != EventRead && event.Type != EventWrite {
+			return
+		}
+		replay.mutex.Lock()
+		replay.records = append(replay.records, ReplayRecord{
+			Direction: event.Type,
+			At:        this.clock.Now().Sub(this.openedAt),
+			Data:      append([]byte(nil), event.Data...),
+		})
+		replay.mutex.Unlock()
+	})
+	return this, replay
+}
+
+// ReplayTo writes the write-direction payloads of a captured session into w.
+// If realtime is true, it waits between payloads for as long as they were
+// apart when captured.
+func ReplayTo(r *Replay, w io.Writer, realtime bool) error {
+	return ReplayDirectionTo(r, w, EventWrite, realtime)
+}
+
+// ReplayDirectionTo is like ReplayTo, but replays the payloads of the given
+// direction (EventRead or EventWrite).
+func ReplayDirectionTo(r *Replay, w io.Writer, direction EventType, realtime bool) error {
+	var previousAt time.Duration
+	for _, record := range r.Records() {
+		if record.Direction != direction {
+			continue
+		}
+		if realtime && record.At > previousAt {
+			sleep(record.At - previousAt)
+		}
+		previousAt = record.At
+		if _, err := w.Write(record.Data); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ReplayLoopback replays a captured client session and a captured server
+// session against each other over an in-memory connection (net.Pipe()), for
+// deterministic end-to-end protocol tests. Each end is wrapped in a logged I/O
+// proxy built by generate, which is called with the label "client" or
+// "server".
+//
+// Each side performs its recorded operations in order: recorded writes are
+// written to its end, and recorded reads read the same number of bytes from
+// its end, which must match the recorded data. Thus the client's writes become
+// the server's reads and vice versa. Timing is not replayed.
+//
+// Once both sides have finished, both ends are closed. The first error from
+// either side is returned, which is a *MismatchError if the data read differs
+// from the recording. If one side fails, both ends are closed immediately so
+// that the other side can't block forever.
+func ReplayLoopback(client, server *Replay,
+	generate func(label string, proxiedObject interface{}) *LoggedIOProxy) error {
+	rawClient, rawServer := net.Pipe()
+	clientConn := generate("client", rawClient)
+	serverConn := generate("server", rawServer)
+	closeBoth := func() {
+		clientConn.Close()
+		serverConn.Close()
+	}
+
+	results := make(chan error, 2)
+	go func() { results <- replaySide(clientConn, client.Records()) }()
+	go func() { results <- replaySide(serverConn, server.Records()) }()
+
+	err := <-results
+	if err != nil {
+		closeBoth()
+	}
+	if secondErr := <-results; err == nil {
+		err = secondErr
+	}
+	closeBoth()
+	return err
+}
+
+// replaySide performs one side's recorded reads and writes on conn.
+func replaySide(conn io.ReadWriter, records []ReplayRecord) error {
+	var readOffset int64
+	for _, record := range records {
+		switch record.Direction {
+		case EventWrite:
+			if _, err := conn.Write(record.Data); err != nil {
+				return err
+			}
+		case EventRead:
+			actual := make([]byte, len(record.Data))
+			if _, err := io.ReadFull(conn, actual); err != nil {
+				return err
+			}
+			if !bytes.Equal(actual, record.Data) {
+				offset := 0
+				for actual[offset] == record.Data[offset] {
+					offset++
+				}
+				return &MismatchError{
+					Offset:   readOffset + int64(offset),
+					Expected: record.Data[offset:],
+					Actual:   actual[offset:],
+				}
+			}
+			readOffset += int64(len(actual))
+		}
+	}
+	return nil
+}
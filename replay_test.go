@@ -0,0 +1,105 @@
+package loggedio
+
+import (
+	"bytes"
+	"testing"
+	"time"
+)
+
+func captureTwoWrites() *Replay {
+	clock := newMockClock()
+	proxy, replay := CaptureReplay(&MockIO{})
+	proxy.SetClock(clock)
+
+	clock.Advance(10 * time.Millisecond)
+	proxy.Write([]byte("hello "))
+	proxy.Read(make([]byte, 3))
+	clock.Advance(20 * time.Millisecond)
+	proxy.Write([]byte("world"))
+	return replay
+}
+
+func recordSleeps() (sleeps *[]time.Duration, restore func()) {
+	sleeps = &[]time.Duration{}
+	sleep = func(d time.Duration) {
+		*sleeps = append(*sleeps, d)
+	}
+	return sleeps, func() { sleep = time.Sleep }
+}
+
+func TestReplayTo(t *testing.T) {
+	sleeps, restore := recordSleeps()
+	defer restore()
+
+	buffer := &bytes.Buffer{}
+	expectNoError(t, ReplayTo(captureTwoWrites(), buffer, false))
+	expectBufferContents(t, buffer, "hello world")
+	expectNumber(t, 0, len(*sleeps))
+}
+
+func TestReplayToRealtime(t *testing.T) {
+	sleeps, restore := recordSleeps()
+	defer restore()
+
+	buffer := &bytes.Buffer{}
+	expectNoError(t, ReplayTo(captureTwoWrites(), buffer, true))
+	expectBufferContents(t, buffer, "hello world")
+	expectNumber(t, 2, len(*sleeps))
+	expectNumber(t, int(10*time.Millisecond), int((*sleeps)[0]))
+	expectNumber(t, int(20*time.Millisecond), int((*sleeps)[1]))
+}
+
+func TestReplayDirectionTo(t *testing.T) {
+	buffer := &bytes.Buffer{}
+	expectNoError(t, ReplayDirectionTo(captureTwoWrites(), buffer, EventRead, false))
+	expectBufferContents(t, buffer, "abc")
+}
+
+func newReplay(records ...ReplayRecord) *Replay {
+	return &Replay{records: records}
+}
+
+func loopbackLogs() (generate func(string, interface{}) *LoggedIOProxy, logs map[string]*SyncBuffer) {
+	logs = map[string]*SyncBuffer{"client": {}, "server": {}}
+	generate = func(label string, o interface{}) *LoggedIOProxy {
+		return StringToWriter(o, logs[label], label+" R [%v]\n", label+" W [%v]\n", "", label+" C\n")
+	}
+	return
+}
+
+func TestReplayLoopback(t *testing.T) {
+	client := newReplay(
+		ReplayRecord{Direction: EventWrite, Data: []byte("PING")},
+		ReplayRecord{Direction: EventRead, Data: []byte("PONG")},
+	)
+	server := newReplay(
+		ReplayRecord{Direction: EventRead, Data: []byte("PING")},
+		ReplayRecord{Direction: EventWrite, Data: []byte("PONG")},
+	)
+	generate, logs := loopbackLogs()
+
+	expectNoError(t, ReplayLoopback(client, server, generate))
+	expectString(t, "client W [PING]\nclient R [PONG]\nclient C\n", logs["client"].String())
+	expectString(t, "server R [PING]\nserver W [PONG]\nserver C\n", logs["server"].String())
+}
+
+func TestReplayLoopbackMismatch(t *testing.T) {
+	client := newReplay(
+		ReplayRecord{Direction: EventWrite, Data: []byte("PING")},
+		ReplayRecord{Direction: EventRead, Data: []byte("PONG")},
+	)
+	server := newReplay(
+		ReplayRecord{Direction: EventRead, Data: []byte("PIN!")},
+		ReplayRecord{Direction: EventWrite, Data: []byte("PONG")},
+	)
+	generate, _ := loopbackLogs()
+
+	err := ReplayLoopback(client, server, generate)
+	mismatch, ok := err.(*MismatchError)
+	if !ok {
+		t.Fatalf("Expected a *MismatchError but got %v", err)
+	}
+	expectNumber(t, 3, int(mismatch.Offset))
+	expectString(t, "!", string(mismatch.Expected))
+	expectString(t, "G", string(mismatch.Actual))
+}
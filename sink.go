@@ -0,0 +1,59 @@
+package loggedio
+
+// Sink receives a proxy's events in addition to its normal reporting. See
+// AddSink().
+type Sink interface {
+	// ReportEvent is called for every event. The event (including its Data)
+	// is only valid for the duration of the call.
+	ReportEvent(event *Event)
+}
+
+// SinkFunc adapts a function to the Sink interface.
+type SinkFunc func(event *Event)
+
+func (this SinkFunc) ReportEvent(event *Event) {
+	this(event)
+}
+
+type sinkEntry struct {
+	id   int
+	sink Sink
+}
+
+// AddSink adds a sink that receives all subsequent events, and returns an ID
+// that can be passed to RemoveSink(). Sinks can be added and removed at any
+// time, including during I/O.
+func (this *LoggedIOProxy) AddSink(sink Sink) (id int) {
+	this.sinksMutex.Lock()
+	defer this.sinksMutex.Unlock()
+	this.nextSinkID++
+	// Copy on write, so that events can be reported to a snapshot of the
+	// sinks without holding the lock.
+	sinks := make([]sinkEntry, 0, len(this.sinks)+1)
+	sinks = append(sinks, this.sinks...)
+	this.sinks = append(sinks, sinkEntry{id: this.nextSinkID, sink: sink})
+	return this.nextSinkID
+}
+
+// RemoveSink removes a sink previously added via AddSink(). It does nothing if
+// there's no such sink.
+func (this *LoggedIOProxy) RemoveSink(id int) {
+	this.sinksMutex.Lock()
+	defer this.sinksMutex.Unlock()
+	sinks := make([]sinkEntry, 0, len(this.sinks))
+	for _, entry := range this.sinks {
+		if entry.id != id {
+			sinks = append(sinks, entry)
+		}
+	}
+	this.sinks = sinks
+}
+
+func (this *LoggedIOProxy) reportToSinks(event *Event) {
+	this.sinksMutex.Lock()
+	sinks := this.sinks
+	this.sinksMutex.Unlock()
+	for _, entry := range sinks {
+		entry.sink.ReportEvent(event)
+	}
+}
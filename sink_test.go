@@ -0,0 +1,39 @@
+package loggedio
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestAddSink(t *testing.T) {
+	buffer := &bytes.Buffer{}
+	logged := StringToWriter(&MockIO{}, buffer, "R [%v]", "W [%v]", "E [%v: %v]", "C")
+
+	logged.Read(make([]byte, 1))
+	sink := &MemorySink{}
+	id := logged.AddSink(sink)
+	logged.Read(make([]byte, 2))
+
+	reads := sink.Reads()
+	expectNumber(t, 1, len(reads))
+	expectString(t, "ab", string(reads[0]))
+	expectBufferContents(t, buffer, "R [a]R [ab]")
+
+	logged.RemoveSink(id)
+	logged.Read(make([]byte, 3))
+	expectNumber(t, 1, len(sink.Reads()))
+}
+
+func TestMultipleSinks(t *testing.T) {
+	logged := StringToWriter(&MockIO{}, &bytes.Buffer{}, "R [%v]", "W [%v]", "E [%v: %v]", "C")
+	var first, second []EventType
+	firstID := logged.AddSink(SinkFunc(func(event *Event) { first = append(first, event.Type) }))
+	logged.AddSink(SinkFunc(func(event *Event) { second = append(second, event.Type) }))
+
+	logged.Write([]byte("a"))
+	logged.RemoveSink(firstID)
+	logged.Close()
+	expectNumber(t, 1, len(first))
+	expectNumber(t, 2, len(second))
+	expectNumber(t, int(EventClose), int(second[1]))
+}
@@ -0,0 +1,125 @@
+package loggedio
+
+import (
+	"fmt"
+	"math/bits"
+	"strings"
+)
+
+// Stats holds the running totals for a proxy's I/O activity.
+type Stats struct {
+	BytesRead    int64
+	BytesWritten int64
+	Errors       int64
+
+	// The number of reads that filled the caller's entire buffer without
+	// error. This is only a heuristic: a high count relative to the bytes read
+	// suggests that the read buffer is too small.
+	BufferLimitedReads int64
+
+	// The number of calls to Read() and Write() that reached the proxied
+	// object.
+	ReadCalls  int64
+	WriteCalls int64
+}
+
+// AverageReadSize returns the average number of bytes per read call, or 0 if
+// there were no read calls.
+func (this Stats) AverageReadSize() float64 {
+	return averageSize(this.BytesRead, this.ReadCalls)
+}
+
+// AverageWriteSize returns the average number of bytes per write call, or 0
+// if there were no write calls.
+func (this Stats) AverageWriteSize() float64 {
+	return averageSize(this.BytesWritten, this.WriteCalls)
+}
+
+func averageSize(bytes, calls int64) float64 {
+	if calls == 0 {
+		return 0
+	}
+	return float64(bytes) / float64(calls)
+}
+
+// Stats returns a snapshot of the proxy's I/O totals so far. For proxies
+// created via Stack(), the totals are those of the inner proxy.
+func (this *LoggedIOProxy) Stats() Stats {
+	if this.statsDelegate != nil {
+		return this.statsDelegate.Stats()
+	}
+	this.mutex.Lock()
+	defer this.mutex.Unlock()
+	return this.stats
+}
+
+// reportCallStats reports the calls made since the previous report.
+func (this *LoggedIOProxy) reportCallStats() {
+	stats := this.Stats()
+	now := this.clock.Now()
+	this.mutex.Lock()
+	interval := now.Sub(this.lastCallStatsAt)
+	delta := Stats{
+		BytesRead:    stats.BytesRead - this.lastCallStats.BytesRead,
+		BytesWritten: stats.BytesWritten - this.lastCallStats.BytesWritten,
+		ReadCalls:    stats.ReadCalls - this.lastCallStats.ReadCalls,
+		WriteCalls:   stats.WriteCalls - this.lastCallStats.WriteCalls,
+	}
+	this.lastCallStats = stats
+	this.lastCallStatsAt = now
+	this.mutex.Unlock()
+	this.reportNotify(fmt.Sprintf("LoggedIO: reads=%v calls in %v avg=%.0fB/call, writes=%v calls in %v avg=%.0fB/call\n",
+		delta.ReadCalls, interval, delta.AverageReadSize(), delta.WriteCalls, interval, delta.AverageWriteSize()))
+}
+
+// The number of read size buckets: 0, and each power of two up to 2^63.
+const readSizeBucketCount = 65
+
+func readSizeBucket(n int) int {
+	if n <= 0 {
+		return 0
+	}
+	return bits.Len(uint(n-1)) + 1
+}
+
+func readSizeBucketBound(bucket int) int {
+	if bucket == 0 {
+		return 0
+	}
+	return 1 << (bucket - 1)
+}
+
+// ReadSizeHistogram returns the number of Read() calls that returned each
+// size, bucketed by powers of two: each key is the upper bound of its bucket,
+// so key 4 counts the reads that returned 3 or 4 bytes, and key 0 counts the
+// reads that returned no data. Empty buckets are omitted.
+func (this *LoggedIOProxy) ReadSizeHistogram() map[int]int {
+	if this.statsDelegate != nil {
+		return this.statsDelegate.ReadSizeHistogram()
+	}
+	this.mutex.Lock()
+	defer this.mutex.Unlock()
+	histogram := make(map[int]int)
+	for bucket, count := range this.readSizes {
+		if count > 0 {
+			histogram[readSizeBucketBound(bucket)] = int(count)
+		}
+	}
+	return histogram
+}
+
+// reportReadSizes reports the read size histogram, for example "LoggedIO: Read
+// sizes: <=1B:1 <=4B:2".
+func (this *LoggedIOProxy) reportReadSizes() {
+	this.mutex.Lock()
+	builder := strings.Builder{}
+	builder.WriteString("LoggedIO: Read sizes:")
+	for bucket, count := range this.readSizes {
+		if count > 0 {
+			fmt.Fprintf(&builder, " <=%vB:%v", readSizeBucketBound(bucket), count)
+		}
+	}
+	this.mutex.Unlock()
+	builder.WriteString("\n")
+	this.reportNotify(builder.String())
+}
@@ -0,0 +1,156 @@
+package loggedio
+
+import (
+	"bytes"
+	"testing"
+	"time"
+)
+
+func TestStats(t *testing.T) {
+	proxied := &MockIO{FailAfterReadByteCount: 2}
+	logged := StringToWriter(proxied, &NullWriter{}, "R [%v]", "W [%v]", "E [%v: %v]", "C")
+
+	logged.Read(make([]byte, 3))
+	logged.Write([]byte("test"))
+
+	stats := logged.Stats()
+	expectNumber(t, 2, int(stats.BytesRead))
+	expectNumber(t, 4, int(stats.BytesWritten))
+	expectNumber(t, 1, int(stats.Errors))
+}
+
+func TestSummaryOnClose(t *testing.T) {
+	proxied := &MockIO{}
+	buffer := &bytes.Buffer{}
+	clock := newMockClock()
+	logged := StringToWriter(proxied, buffer, "", "", "E [%v: %v]", "C\n")
+	logged.SummaryOnClose = true
+	logged.SetClock(clock)
+
+	logged.Read(make([]byte, 3))
+	logged.Write([]byte("test"))
+	clock.Advance(1200 * time.Millisecond)
+	expectNoError(t, logged.Close())
+	expectBufferContents(t, buffer, "C\nSUMMARY read=3 write=4 errors=0 dur=1.2s\n")
+}
+
+func TestLifetimeWithoutIO(t *testing.T) {
+	buffer := &bytes.Buffer{}
+	clock := newMockClock()
+	logged := StringToWriter(&MockIO{}, buffer, "", "", "E [%v: %v]", "C\n")
+	logged.SummaryOnClose = true
+	logged.LifetimeFromFirstActivity = true
+	logged.SetClock(clock)
+
+	clock.Advance(5 * time.Second)
+	expectNoError(t, logged.Close())
+	clock.Advance(time.Minute)
+	expectBufferContents(t, buffer, "C\nSUMMARY read=0 write=0 errors=0 dur=5s\n")
+	if lifetime := logged.Lifetime(); lifetime != 5*time.Second {
+		t.Errorf("Expected lifetime 5s but got %v", lifetime)
+	}
+}
+
+func TestLifetimeFromFirstActivity(t *testing.T) {
+	buffer := &bytes.Buffer{}
+	clock := newMockClock()
+	logged := StringToWriter(&MockIO{}, buffer, "", "", "E [%v: %v]", "C\n")
+	logged.SummaryOnClose = true
+	logged.LifetimeFromFirstActivity = true
+	logged.SetClock(clock)
+
+	clock.Advance(2 * time.Second)
+	logged.Write([]byte("test"))
+	clock.Advance(3 * time.Second)
+	logged.Read(make([]byte, 3))
+	clock.Advance(500 * time.Millisecond)
+	expectNoError(t, logged.Close())
+	expectBufferContents(t, buffer, "C\nSUMMARY read=3 write=4 errors=0 dur=3.5s\n")
+}
+
+func TestBufferLimitedReads(t *testing.T) {
+	buffer := &bytes.Buffer{}
+	logged := StringToWriter(&MockIO{}, buffer, "R [%v]\n", "W [%v]\n", "E [%v: %v]\n", "C\n")
+	for i := 0; i < 3; i++ {
+		logged.Read(make([]byte, 3))
+	}
+	expectNumber(t, 3, int(logged.Stats().BufferLimitedReads))
+
+	logged.Write([]byte("test"))
+	expectNumber(t, 3, int(logged.Stats().BufferLimitedReads))
+
+	buffer.Reset()
+	logged.ReportBufferLimitedReads = true
+	logged.Read(make([]byte, 3))
+	expectNumber(t, 4, int(logged.Stats().BufferLimitedReads))
+	expectBufferContents(t, buffer, "R [abc]\nLoggedIO: Read() filled its 3 byte buffer\n")
+}
+
+func TestCallCounts(t *testing.T) {
+	logged := StringToWriter(&MockIO{}, &NullWriter{}, "R [%v]", "W [%v]", "E [%v: %v]", "C")
+	for i := 0; i < 4; i++ {
+		logged.Read(make([]byte, 2))
+	}
+	logged.Read(make([]byte, 8))
+	logged.Write([]byte("test"))
+
+	stats := logged.Stats()
+	expectNumber(t, 5, int(stats.ReadCalls))
+	expectNumber(t, 1, int(stats.WriteCalls))
+	if stats.AverageReadSize() != 3.2 {
+		t.Errorf("Expected average read size 3.2 but got %v", stats.AverageReadSize())
+	}
+	if stats.AverageWriteSize() != 4 {
+		t.Errorf("Expected average write size 4 but got %v", stats.AverageWriteSize())
+	}
+	if (Stats{}).AverageReadSize() != 0 {
+		t.Errorf("Expected average read size 0 with no calls")
+	}
+}
+
+func TestReportCallStatsEvery(t *testing.T) {
+	buffer := &SyncBuffer{}
+	logged := StringToWriter(&MockIO{}, buffer, "", "", "", "C\n")
+	clock := newMockClock()
+	logged.SetClock(clock)
+	logged.ReportCallStatsEvery = time.Minute
+
+	for i := 0; i < 4; i++ {
+		logged.Read(make([]byte, 16))
+	}
+	clock.Advance(time.Minute)
+	waitForSuffix(t, buffer, "\n")
+	logged.Write([]byte("test"))
+	clock.Advance(time.Minute)
+	waitForSuffix(t, buffer, "writes=1 calls in 1m0s avg=4B/call\n")
+	logged.Close()
+	expectString(t, ""+
+		"LoggedIO: reads=4 calls in 1m0s avg=16B/call, writes=0 calls in 1m0s avg=0B/call\n"+
+		"LoggedIO: reads=0 calls in 1m0s avg=0B/call, writes=1 calls in 1m0s avg=4B/call\n"+
+		"C\n", buffer.String())
+}
+
+func TestReadSizeHistogram(t *testing.T) {
+	buffer := &bytes.Buffer{}
+	logged := StringToWriter(&MockIO{}, buffer, "", "", "E [%v: %v]\n", "C\n")
+	logged.SummaryOnClose = true
+	logged.ReadSizesInSummary = true
+	logged.SetClock(newMockClock())
+
+	for _, size := range []int{1, 3, 3} {
+		logged.Read(make([]byte, size))
+	}
+	logged.Read(nil)
+	logged.Write([]byte("test"))
+
+	histogram := logged.ReadSizeHistogram()
+	expectNumber(t, 3, len(histogram))
+	expectNumber(t, 1, histogram[0])
+	expectNumber(t, 1, histogram[1])
+	expectNumber(t, 2, histogram[4])
+
+	expectNoError(t, logged.Close())
+	expectBufferContents(t, buffer, "C\n"+
+		"SUMMARY read=7 write=4 errors=0 dur=0s\n"+
+		"LoggedIO: Read sizes: <=0B:1 <=1B:1 <=4B:2\n")
+}
@@ -0,0 +1,67 @@
+package loggedio
+
+import (
+	"io"
+	"strings"
+)
+
+// Payloads larger than this are hex encoded directly to writer targets in
+// chunks rather than being encoded into a single string first, so that memory
+// use stays bounded regardless of payload size.
+const hexStreamingThreshold = 4096
+
+// The number of payload bytes encoded per chunk when streaming hex.
+const hexStreamingChunkSize = 1024
+
+// writeHex writes the same output as toHex(b) to writer, using a fixed size
+// buffer.
+func writeHex(writer io.Writer, b []byte) (err error) {
+	var buffer [hexStreamingChunkSize * 3]byte
+	for len(b) > 0 {
+		chunkSize := len(b)
+		if chunkSize > hexStreamingChunkSize {
+			chunkSize = hexStreamingChunkSize
+		}
+		encoded := buffer[:0]
+		for _, ch := range b[:chunkSize] {
+			encoded = append(encoded, hexDigits[ch>>4], hexDigits[ch&15], ' ')
+		}
+		b = b[chunkSize:]
+		if len(b) == 0 {
+			encoded = encoded[:len(encoded)-1]
+		}
+		if _, err = writer.Write(encoded); err != nil {
+			return
+		}
+	}
+	return
+}
+
+// splitFormatAtArg splits a printf style format around the verb that consumes
+// argument argIndex, which must be a plain %v or %s and the final verb in the
+// format. The prefix is returned as a format (for the preceding arguments),
+// and the suffix as literal text. ok is false if the format can't be split.
+func splitFormatAtArg(format string, argIndex int) (prefix, suffix string, ok bool) {
+	verbIndex := 0
+	for i := 0; i < len(format); i++ {
+		if format[i] != '%' {
+			continue
+		}
+		if i+1 < len(format) && format[i+1] == '%' {
+			i++
+			continue
+		}
+		if verbIndex == argIndex {
+			if i+1 >= len(format) || (format[i+1] != 'v' && format[i+1] != 's') {
+				return
+			}
+			suffix = format[i+2:]
+			if strings.Count(suffix, "%") != 2*strings.Count(suffix, "%%") {
+				return "", "", false
+			}
+			return format[:i], strings.Replace(suffix, "%%", "%", -1), true
+		}
+		verbIndex++
+	}
+	return
+}
@@ -0,0 +1,79 @@
+package loggedio
+
+import (
+	"bytes"
+	"io/ioutil"
+	"runtime"
+	"sync"
+	"testing"
+)
+
+func TestSplitFormatAtArg(t *testing.T) {
+	assertSplit := func(format string, argIndex int, expectedPrefix, expectedSuffix string, expectedOK bool) {
+		prefix, suffix, ok := splitFormatAtArg(format, argIndex)
+		if prefix != expectedPrefix || suffix != expectedSuffix || ok != expectedOK {
+			t.Errorf("%v: Expected (%v, %v, %v) but got (%v, %v, %v)", format, expectedPrefix,
+				expectedSuffix, expectedOK, prefix, suffix, ok)
+		}
+	}
+	assertSplit("R [%v]", 0, "R [", "]", true)
+	assertSplit("%v R [%v] 100%%", 1, "%v R [", "] 100%", true)
+	assertSplit("R [%x]", 0, "", "", false)
+	assertSplit("R [%v] %v", 0, "", "", false)
+	assertSplit("R", 0, "", "", false)
+}
+
+func TestLargeHexPayload(t *testing.T) {
+	payload := generateBytes(hexStreamingThreshold*3 + 7)
+	buffer := &bytes.Buffer{}
+	logged := HexToWriter(&MockIO{}, buffer, "R [%v]", "%v W [%v]\n", "E [%v: %v]", "C")
+	logged.SequenceInText = true
+
+	logged.Write(payload)
+	expectBufferContents(t, buffer, "1 W ["+toHex(payload)+"]\n")
+}
+
+func TestLargeHexPayloadBoundedAllocation(t *testing.T) {
+	payload := generateBytes(10 * 1024 * 1024)
+	logged := HexToWriter(ioutil.Discard, ioutil.Discard, "R [%v]", "W [%v]", "E [%v: %v]", "C")
+
+	var before, after runtime.MemStats
+	runtime.ReadMemStats(&before)
+	logged.Write(payload)
+	runtime.ReadMemStats(&after)
+
+	allocated := after.TotalAlloc - before.TotalAlloc
+	if allocated > uint64(len(payload))/10 {
+		t.Errorf("Expected bounded allocation for a %v byte payload but allocated %v bytes",
+			len(payload), allocated)
+	}
+}
+
+func TestConcurrentLargeHexPayloadsNotInterleaved(t *testing.T) {
+	payload := generateBytes(hexStreamingThreshold * 4)
+	buffer := &bytes.Buffer{}
+	logged := HexToWriter(ioutil.Discard, buffer, "R [%v]\n", "W [%v]\n", "E [%v: %v]\n", "C\n")
+
+	var waitGroup sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		waitGroup.Add(1)
+		go func() {
+			defer waitGroup.Done()
+			logged.Write(payload)
+		}()
+	}
+	waitGroup.Wait()
+
+	line := "W [" + toHex(payload) + "]\n"
+	expectBufferContents(t, buffer, line+line+line+line)
+}
+
+func BenchmarkLargeHexPayload(b *testing.B) {
+	payload := generateBytes(10 * 1024 * 1024)
+	logged := HexToWriter(ioutil.Discard, ioutil.Discard, "R [%v]", "W [%v]", "E [%v: %v]", "C")
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		logged.Write(payload)
+	}
+}
@@ -0,0 +1,121 @@
+package loggedio
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"unicode/utf8"
+)
+
+// TableColumn identifies a column in the output of TableToWriter.
+type TableColumn int
+
+const (
+	// The time of the event according to the proxy's clock, as
+	// "15:04:05.000".
+	TableColumnTime TableColumn = iota
+	// The event type, using the proxy's DirectionLabels for reads and writes.
+	TableColumnDirection
+	// The payload length of reads and writes.
+	TableColumnBytes
+	// The payload of reads and writes (as text if mostly printable, or hex
+	// otherwise, according to the proxy's TextThreshold), the location and
+	// error of errors, or the message of notifications.
+	TableColumnData
+)
+
+// DefaultTableColumns are the columns used by TableToWriter if none are given.
+var DefaultTableColumns = []TableColumn{
+	TableColumnTime,
+	TableColumnDirection,
+	TableColumnBytes,
+	TableColumnData,
+}
+
+var tableColumnWidths = map[TableColumn]int{
+	TableColumnTime:      12,
+	TableColumnDirection: 6,
+	TableColumnBytes:     7,
+	TableColumnData:      64,
+}
+
+const tableColumnSeparator = " | "
+
+// TableToWriter creates a logged I/O proxy that writes each event to writer as
+// a row of fixed-width columns separated by " | ", so that the columns line up
+// across rows:
+//
+//	12:00:00.000 | write  |       5 | hello
+//	12:00:00.015 | read   |       3 | 01 02 03
+//
+// columns selects the columns and their order (DefaultTableColumns if none are
+// given). Values that are too wide for their column are truncated and end in
+// "…". The last column is neither padded nor truncated.
+func TableToWriter(proxiedObject interface{}, writer io.Writer, columns ...TableColumn) *LoggedIOProxy {
+	if len(columns) == 0 {
+		columns = DefaultTableColumns
+	}
+	target := newReportTarget(writer)
+	var this *LoggedIOProxy
+	this = newProxy(proxiedObject, func(event *Event) {
+		builder := strings.Builder{}
+		for i, column := range columns {
+			if i > 0 {
+				builder.WriteString(tableColumnSeparator)
+			}
+			value := this.tableValue(column, event)
+			if i < len(columns)-1 {
+				value = fitToWidth(value, tableColumnWidths[column], column == TableColumnBytes)
+			}
+			builder.WriteString(value)
+		}
+		io.WriteString(target, strings.TrimRight(builder.String(), " ")+"\n")
+	})
+	this.target = target
+	return this
+}
+
+func (this *LoggedIOProxy) tableValue(column TableColumn, event *Event) string {
+	switch column {
+	case TableColumnTime:
+		return this.clock.Now().Format("15:04:05.000")
+	case TableColumnDirection:
+		switch event.Type {
+		case EventRead:
+			return this.DirectionLabels.Read
+		case EventWrite:
+			return this.DirectionLabels.Write
+		default:
+			return event.Type.String()
+		}
+	case TableColumnBytes:
+		if event.Type == EventRead || event.Type == EventWrite {
+			return fmt.Sprintf("%v", len(event.Data))
+		}
+		return ""
+	case TableColumnData:
+		switch event.Type {
+		case EventRead, EventWrite:
+			return encodeAuto(event.Data, this.TextThreshold)
+		case EventError:
+			return fmt.Sprintf("%v: %v", event.Location, event.Err)
+		case EventNotify:
+			return strings.TrimRight(event.Message, "\n")
+		}
+	}
+	return ""
+}
+
+// fitToWidth pads or truncates s to exactly width characters.
+func fitToWidth(s string, width int, alignRight bool) string {
+	length := utf8.RuneCountInString(s)
+	if length > width {
+		runes := []rune(s)
+		return string(runes[:width-1]) + "…"
+	}
+	padding := strings.Repeat(" ", width-length)
+	if alignRight {
+		return padding + s
+	}
+	return s + padding
+}
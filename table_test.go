@@ -0,0 +1,39 @@
+package loggedio
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestTableToWriter(t *testing.T) {
+	buffer := &bytes.Buffer{}
+	logged := TableToWriter(&MockIO{}, buffer)
+	clock := newMockClock()
+	logged.SetClock(clock)
+
+	logged.Write([]byte("hello"))
+	clock.Advance(15 * time.Millisecond)
+	logged.Read(make([]byte, 3))
+	logged.Close()
+	expectBufferContents(t, buffer, ""+
+		"00:00:00.000 | write  |       5 | hello\n"+
+		"00:00:00.015 | read   |       3 | abc\n"+
+		"00:00:00.015 | close  |         |\n")
+
+	lines := strings.Split(buffer.String(), "\n")
+	expectNumber(t, strings.Index(lines[0], "| 5"), strings.Index(lines[1], "| 3"))
+}
+
+func TestTableColumns(t *testing.T) {
+	buffer := &bytes.Buffer{}
+	logged := TableToWriter(&MockIO{}, buffer, TableColumnDirection, TableColumnData)
+	logged.DirectionLabels = DirectionLabels{Read: "inbound", Write: "out"}
+
+	logged.Write([]byte{0, 1})
+	logged.Read(make([]byte, 2))
+	expectBufferContents(t, buffer, ""+
+		"out    | 00 01\n"+
+		"inbou… | ab\n")
+}
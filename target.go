@@ -0,0 +1,288 @@
+package loggedio
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// reportTarget is the writer that writer-backed proxies report to. It's an
+// indirection that allows the underlying writer to be buffered after
+// construction. All writes are serialized.
+type reportTarget struct {
+	mutex         sync.Mutex
+	writer        io.Writer
+	buffer        *bufio.Writer
+	stopFlushing  chan bool
+	flushFinished chan bool
+	timeouts      *timeoutWriter
+	// Written to each writer set via setWriter(), for formats that start
+	// with a file header.
+	header []byte
+	// Whether the writer (before any wrapping) is a terminal, for ColorAuto.
+	isTerminal bool
+}
+
+func newReportTarget(writer io.Writer) *reportTarget {
+	return &reportTarget{writer: writer, isTerminal: isTerminal(writer)}
+}
+
+func (this *reportTarget) writesToTerminal() bool {
+	this.mutex.Lock()
+	defer this.mutex.Unlock()
+	return this.isTerminal
+}
+
+func (this *reportTarget) Write(b []byte) (n int, err error) {
+	this.mutex.Lock()
+	defer this.mutex.Unlock()
+	return this.writeLocked(b)
+}
+
+func (this *reportTarget) writeLocked(b []byte) (n int, err error) {
+	if this.buffer != nil {
+		return this.buffer.Write(b)
+	}
+	return this.writer.Write(b)
+}
+
+func (this *reportTarget) startBuffering(size int, flushInterval time.Duration) {
+	this.mutex.Lock()
+	defer this.mutex.Unlock()
+	if this.buffer != nil {
+		this.buffer.Flush()
+	}
+	this.buffer = bufio.NewWriterSize(this.writer, size)
+	if flushInterval > 0 && this.stopFlushing == nil {
+		this.stopFlushing = make(chan bool)
+		this.flushFinished = make(chan bool)
+		go this.flushPeriodically(flushInterval, this.stopFlushing, this.flushFinished)
+	}
+}
+
+func (this *reportTarget) flushPeriodically(interval time.Duration, stop <-chan bool, finished chan<- bool) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	defer close(finished)
+	for {
+		select {
+		case <-ticker.C:
+			this.flush()
+		case <-stop:
+			return
+		}
+	}
+}
+
+func (this *reportTarget) flush() error {
+	this.mutex.Lock()
+	defer this.mutex.Unlock()
+	if this.buffer == nil {
+		return nil
+	}
+	return this.buffer.Flush()
+}
+
+// close stops periodic flushing, flushes any buffered output, and reverts to
+// unbuffered writes so that anything reported afterwards (such as errors from
+// operations unblocked by the close) still reaches the writer.
+func (this *reportTarget) close() (err error) {
+	this.mutex.Lock()
+	stopFlushing := this.stopFlushing
+	flushFinished := this.flushFinished
+	this.stopFlushing = nil
+	this.flushFinished = nil
+	this.mutex.Unlock()
+	if stopFlushing != nil {
+		close(stopFlushing)
+		<-flushFinished
+	}
+
+	this.mutex.Lock()
+	defer this.mutex.Unlock()
+	if this.buffer != nil {
+		err = this.buffer.Flush()
+		this.buffer = nil
+	}
+	return
+}
+
+// BufferOutput wraps the proxy's report writer in a bufio.Writer of the
+// specified size, which coalesces the many small writes caused by frequent
+// events. The buffer is flushed every flushInterval (if > 0), when Flush() is
+// called, and when the proxy is closed.
+//
+// Buffering happens after formatting, and is only supported by proxies that
+// report to an io.Writer (StringToWriter, HexToWriter, JSONToWriter, etc).
+// An error is returned for other proxies.
+func (this *LoggedIOProxy) BufferOutput(size int, flushInterval time.Duration) error {
+	if this.target == nil {
+		return fmt.Errorf("LoggedIO: this proxy doesn't report to an io.Writer")
+	}
+	this.target.startBuffering(size, flushInterval)
+	return nil
+}
+
+// SetTarget replaces the writer that the proxy reports to (for example with a
+// newly reopened file after an external log rotation). Any buffered output is
+// flushed to the old writer first, and the old writer is left open. Options
+// applied via BufferOutput() and SetReportTimeout() carry over to the new
+// writer. It's safe to call concurrently with I/O.
+//
+// Only proxies that report to an io.Writer (StringToWriter, HexToWriter,
+// JSONToWriter, etc) are supported. An error is returned for other proxies.
+func (this *LoggedIOProxy) SetTarget(writer io.Writer) error {
+	if this.target == nil {
+		return fmt.Errorf("LoggedIO: this proxy doesn't report to an io.Writer")
+	}
+	return this.target.setWriter(writer)
+}
+
+func (this *reportTarget) setWriter(writer io.Writer) (err error) {
+	this.mutex.Lock()
+	defer this.mutex.Unlock()
+	if this.buffer != nil {
+		err = this.buffer.Flush()
+	}
+	this.isTerminal = isTerminal(writer)
+	if this.timeouts != nil {
+		timeouts := newTimeoutWriter(writer)
+		timeouts.timeout = this.timeouts.timeout
+		timeouts.dropped = atomic.LoadUint64(&this.timeouts.dropped)
+		this.timeouts = timeouts
+		writer = timeouts
+	}
+	this.writer = writer
+	if this.buffer != nil {
+		this.buffer = bufio.NewWriterSize(this.writer, this.buffer.Size())
+	}
+	if this.header != nil {
+		if _, headerErr := this.writeLocked(this.header); err == nil {
+			err = headerErr
+		}
+	}
+	return
+}
+
+// SetReportTimeout limits how long a write to the proxy's report writer may
+// take, so that a blocked writer (such as a full pipe) can't stall the proxied
+// I/O. A report write that hasn't completed within timeout is abandoned and
+// counted (see DroppedReports()). It's left to complete in the background,
+// and subsequent report writes wait (within their own timeout) for it to
+// finish, so output is never reordered. A timeout of 0 waits indefinitely.
+//
+// Only proxies that report to an io.Writer (StringToWriter, HexToWriter,
+// JSONToWriter, etc) are supported. An error is returned for other proxies.
+func (this *LoggedIOProxy) SetReportTimeout(timeout time.Duration) error {
+	if this.target == nil {
+		return fmt.Errorf("LoggedIO: this proxy doesn't report to an io.Writer")
+	}
+	this.target.setTimeout(timeout)
+	return nil
+}
+
+// DroppedReports returns the number of report writes abandoned because they
+// exceeded the report timeout (see SetReportTimeout()). A single event may be
+// written in more than one report write.
+func (this *LoggedIOProxy) DroppedReports() uint64 {
+	if this.target == nil {
+		return 0
+	}
+	this.target.mutex.Lock()
+	timeouts := this.target.timeouts
+	this.target.mutex.Unlock()
+	if timeouts == nil {
+		return 0
+	}
+	return atomic.LoadUint64(&timeouts.dropped)
+}
+
+func (this *reportTarget) setTimeout(timeout time.Duration) {
+	this.mutex.Lock()
+	defer this.mutex.Unlock()
+	if this.timeouts == nil {
+		this.timeouts = newTimeoutWriter(this.writer)
+		this.writer = this.timeouts
+		if this.buffer != nil {
+			this.buffer.Flush()
+			this.buffer = bufio.NewWriterSize(this.writer, this.buffer.Size())
+		}
+	}
+	this.timeouts.timeout = timeout
+}
+
+// timeoutWriter writes to its writer from a separate goroutine, giving up on
+// writes that take longer than timeout. Writes must be serialized by the
+// caller.
+type timeoutWriter struct {
+	writer  io.Writer
+	timeout time.Duration
+	// Holds a token while no write is in progress.
+	idle    chan struct{}
+	dropped uint64
+}
+
+type timeoutWriteResult struct {
+	n   int
+	err error
+}
+
+func newTimeoutWriter(writer io.Writer) *timeoutWriter {
+	this := &timeoutWriter{
+		writer: writer,
+		idle:   make(chan struct{}, 1),
+	}
+	this.idle <- struct{}{}
+	return this
+}
+
+func (this *timeoutWriter) Write(b []byte) (n int, err error) {
+	if this.timeout <= 0 {
+		<-this.idle
+		defer func() { this.idle <- struct{}{} }()
+		return this.writer.Write(b)
+	}
+
+	timer := time.NewTimer(this.timeout)
+	defer timer.Stop()
+	select {
+	case <-this.idle:
+	case <-timer.C:
+		atomic.AddUint64(&this.dropped, 1)
+		return len(b), nil
+	}
+
+	// The caller may reuse b once this returns, even if the write is still in
+	// progress.
+	data := append([]byte(nil), b...)
+	result := make(chan timeoutWriteResult, 1)
+	go func() {
+		n, err := this.writer.Write(data)
+		result <- timeoutWriteResult{n, err}
+		this.idle <- struct{}{}
+	}()
+	select {
+	case written := <-result:
+		return written.n, written.err
+	case <-timer.C:
+		// Pretend that it succeeded, since writers such as bufio.Writer never
+		// recover from an error.
+		atomic.AddUint64(&this.dropped, 1)
+		return len(b), nil
+	}
+}
+
+// Flush writes any combined writes (see CombineWrites()) to the proxied object,
+// and then any buffered report output to the underlying writer.
+func (this *LoggedIOProxy) Flush() (err error) {
+	err = this.flushCombinedWrites()
+	if this.target != nil {
+		if flushErr := this.target.flush(); err == nil {
+			err = flushErr
+		}
+	}
+	return
+}
@@ -0,0 +1,142 @@
+package loggedio
+
+import (
+	"bytes"
+	"testing"
+	"time"
+)
+
+func TestBufferOutput(t *testing.T) {
+	proxied := &MockIO{}
+	buffer := &bytes.Buffer{}
+	logged := StringToWriter(proxied, buffer, "R [%v]", "W [%v]", "E [%v: %v]", "C")
+	expectNoError(t, logged.BufferOutput(1024, 0))
+
+	logged.Read(make([]byte, 3))
+	logged.Write([]byte("test"))
+	expectBufferContents(t, buffer, "")
+	expectNoError(t, logged.Flush())
+	expectBufferContents(t, buffer, "R [abc]W [test]")
+
+	buffer.Reset()
+	logged.Read(make([]byte, 3))
+	expectBufferContents(t, buffer, "")
+	expectNoError(t, logged.Close())
+	expectBufferContents(t, buffer, "R [abc]C")
+
+	buffer.Reset()
+	logged.Read(make([]byte, 3))
+	expectBufferContents(t, buffer, "R [abc]")
+}
+
+func TestBufferOutputPeriodicFlush(t *testing.T) {
+	proxied := &MockIO{}
+	buffer := &SyncBuffer{}
+	logged := StringToWriter(proxied, buffer, "R [%v]", "W [%v]", "E [%v: %v]", "C")
+	expectNoError(t, logged.BufferOutput(1024, time.Millisecond))
+
+	logged.Read(make([]byte, 3))
+	for i := 0; i < 1000 && buffer.String() == ""; i++ {
+		time.Sleep(time.Millisecond)
+	}
+	if buffer.String() != "R [abc]" {
+		t.Errorf("Expected periodic flush to write \"R [abc]\" but found \"%v\"", buffer.String())
+	}
+	expectNoError(t, logged.Close())
+}
+
+func TestBufferOutputUnsupported(t *testing.T) {
+	logged := StringToLog(&MockIO{}, "R [%v]", "W [%v]", "E [%v: %v]", "C")
+	expectError(t, logged.BufferOutput(1024, 0))
+}
+
+// MockBlockingWriter blocks every write until release is closed.
+type MockBlockingWriter struct {
+	release chan struct{}
+	buffer  SyncBuffer
+}
+
+func (this *MockBlockingWriter) Write(b []byte) (n int, err error) {
+	<-this.release
+	return this.buffer.Write(b)
+}
+
+func TestReportTimeout(t *testing.T) {
+	writer := &MockBlockingWriter{release: make(chan struct{})}
+	logged := StringToWriter(&MockIO{}, writer, "R [%v]\n", "W [%v]\n", "E [%v: %v]\n", "C\n")
+	expectNoError(t, logged.SetReportTimeout(10*time.Millisecond))
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		logged.Read(make([]byte, 3))
+		logged.Write([]byte("test"))
+	}()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatalf("I/O blocked on the report writer")
+	}
+	expectNumber(t, 2, int(logged.DroppedReports()))
+
+	// The abandoned first write completes in the background once the writer
+	// unblocks, and the next write waits for it.
+	close(writer.release)
+	expectNoError(t, logged.SetReportTimeout(5*time.Second))
+	logged.Read(make([]byte, 3))
+	expectNumber(t, 2, int(logged.DroppedReports()))
+	expectString(t, "R [abc]\nR [abc]\n", writer.buffer.String())
+}
+
+func TestReportTimeoutUnsupported(t *testing.T) {
+	logged := StringToLog(&MockIO{}, "R [%v]", "W [%v]", "E [%v: %v]", "C")
+	expectError(t, logged.SetReportTimeout(time.Second))
+	expectNumber(t, 0, int(logged.DroppedReports()))
+}
+
+func TestSetTarget(t *testing.T) {
+	bufferA := &bytes.Buffer{}
+	bufferB := &bytes.Buffer{}
+	logged := StringToWriter(&MockIO{}, bufferA, "R [%v]\n", "W [%v]\n", "E [%v: %v]\n", "C\n")
+
+	logged.Write([]byte("one"))
+	expectNoError(t, logged.SetTarget(bufferB))
+	logged.Write([]byte("two"))
+	expectBufferContents(t, bufferA, "W [one]\n")
+	expectBufferContents(t, bufferB, "W [two]\n")
+}
+
+func TestSetTargetFlushesBuffer(t *testing.T) {
+	bufferA := &bytes.Buffer{}
+	bufferB := &bytes.Buffer{}
+	logged := StringToWriter(&MockIO{}, bufferA, "R [%v]\n", "W [%v]\n", "E [%v: %v]\n", "C\n")
+	expectNoError(t, logged.BufferOutput(1024, 0))
+
+	logged.Write([]byte("one"))
+	expectBufferContents(t, bufferA, "")
+	expectNoError(t, logged.SetTarget(bufferB))
+	expectBufferContents(t, bufferA, "W [one]\n")
+	logged.Write([]byte("two"))
+	expectBufferContents(t, bufferB, "")
+	expectNoError(t, logged.Flush())
+	expectBufferContents(t, bufferB, "W [two]\n")
+}
+
+func TestSetTargetPcapHeader(t *testing.T) {
+	bufferA := &bytes.Buffer{}
+	bufferB := &bytes.Buffer{}
+	logged := DumpToPcap(&MockIO{}, bufferA)
+	logged.Write([]byte("one"))
+	expectNoError(t, logged.SetTarget(bufferB))
+	logged.Write([]byte("two"))
+	expectNumber(t, 24+16+4, bufferA.Len())
+	expectNumber(t, 24+16+4, bufferB.Len())
+	if !bytes.Equal(bufferA.Bytes()[:24], bufferB.Bytes()[:24]) {
+		t.Errorf("Expected the new writer to start with the pcap header")
+	}
+}
+
+func TestSetTargetUnsupported(t *testing.T) {
+	logged := StringToLog(&MockIO{}, "R [%v]", "W [%v]", "E [%v: %v]", "C")
+	expectError(t, logged.SetTarget(&bytes.Buffer{}))
+}
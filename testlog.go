@@ -0,0 +1,22 @@
+package loggedio
+
+import (
+	"testing"
+)
+
+// ToTestLog creates a logged I/O proxy that reports all events via tb.Logf(),
+// so that the output is attributed to the running test and only shown when
+// the test fails (or when running verbosely). Read and write payloads are
+// rendered using the specified encoding.
+//
+// Events are logged as "R [payload]", "W [payload]", "E [location: error]",
+// and "C". If the SequenceInText or ReportWriteToReadLatency options are set,
+// the sequence number and latency annotation are prepended automatically (for
+// example "3 (+2ms since write) R [payload]").
+func ToTestLog(proxiedObject interface{}, tb testing.TB, encoding Encoding) *LoggedIOProxy {
+	formatter := newTextFormatter(proxiedObject, tb.Logf, nil, encoding,
+		"R [%v]", "W [%v]", "E [%v: %v]", "C")
+	formatter.addLeadingVerbs = true
+	formatter.targetDescription = "testing.TB"
+	return formatter.proxy
+}
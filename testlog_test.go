@@ -0,0 +1,49 @@
+package loggedio
+
+import (
+	"fmt"
+	"testing"
+	"time"
+)
+
+type FakeTB struct {
+	testing.TB
+	Messages []string
+}
+
+func (this *FakeTB) Logf(format string, args ...interface{}) {
+	this.Messages = append(this.Messages, fmt.Sprintf(format, args...))
+}
+
+func TestToTestLog(t *testing.T) {
+	tb := &FakeTB{}
+	logged := ToTestLog(&MockIO{FailAfterReadByteCount: 3}, tb, EncodingHex)
+	logged.Read(make([]byte, 3))
+	logged.Close()
+
+	expectMessages(t, tb, "R [61 62 63]", "E [Read(): ERROR!]", "C")
+}
+
+func TestToTestLogLeadingArgs(t *testing.T) {
+	tb := &FakeTB{}
+	clock := newMockClock()
+	logged := ToTestLog(&MockIO{}, tb, EncodingString)
+	logged.SequenceInText = true
+	logged.ReportWriteToReadLatency = true
+	logged.SetClock(clock)
+
+	logged.Write([]byte("test"))
+	clock.Advance(time.Millisecond)
+	logged.Read(make([]byte, 3))
+	logged.Read(make([]byte, 3))
+	expectMessages(t, tb, "1 W [test]", "2 (+1ms since write) R [abc]", "3 R [abc]")
+}
+
+func expectMessages(t *testing.T, tb *FakeTB, expected ...string) {
+	expectNumber(t, len(expected), len(tb.Messages))
+	for i, message := range tb.Messages {
+		if i < len(expected) && message != expected[i] {
+			t.Errorf("Expected message \"%v\" but got \"%v\"", expected[i], message)
+		}
+	}
+}
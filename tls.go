@@ -0,0 +1,70 @@
+package loggedio
+
+import (
+	"fmt"
+	"sync"
+)
+
+const tlsRecordHeaderLength = 5
+
+var tlsContentTypeNames = map[byte]string{
+	20: "change_cipher_spec",
+	21: "alert",
+	22: "handshake",
+	23: "application_data",
+	24: "heartbeat",
+}
+
+// tlsRecordTracker follows TLS record boundaries across reads.
+type tlsRecordTracker struct {
+	mutex sync.Mutex
+	// Header bytes of the next record received so far.
+	header []byte
+	// Body bytes of the current record that haven't been read yet.
+	remaining int
+	// Set once the stream is found not to be TLS.
+	gaveUp bool
+}
+
+// feed consumes read bytes, and returns an annotation for each record header
+// that they complete.
+func (this *tlsRecordTracker) feed(b []byte) (annotations []string) {
+	this.mutex.Lock()
+	defer this.mutex.Unlock()
+	for len(b) > 0 && !this.gaveUp {
+		if this.remaining > 0 {
+			skip := this.remaining
+			if skip > len(b) {
+				skip = len(b)
+			}
+			this.remaining -= skip
+			b = b[skip:]
+			continue
+		}
+
+		needed := tlsRecordHeaderLength - len(this.header)
+		if needed > len(b) {
+			needed = len(b)
+		}
+		this.header = append(this.header, b[:needed]...)
+		b = b[needed:]
+		if len(this.header) < tlsRecordHeaderLength {
+			break
+		}
+
+		contentType := this.header[0]
+		name, ok := tlsContentTypeNames[contentType]
+		if !ok || this.header[1] != 3 {
+			annotations = append(annotations,
+				fmt.Sprintf("LoggedIO: TLS: Not a TLS record header: %v\n", toHex(this.header)))
+			this.gaveUp = true
+			break
+		}
+		length := int(this.header[3])<<8 | int(this.header[4])
+		annotations = append(annotations,
+			fmt.Sprintf("LoggedIO: TLS %v len=%v version=%v.%v\n", name, length, this.header[1], this.header[2]))
+		this.remaining = length
+		this.header = this.header[:0]
+	}
+	return
+}
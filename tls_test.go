@@ -0,0 +1,42 @@
+package loggedio
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestTLSRecordAware(t *testing.T) {
+	stream := []byte{
+		// Handshake record, TLS 1.0, 4 bytes
+		22, 3, 1, 0, 4, 1, 2, 3, 4,
+		// Application data record, TLS 1.2, 0x564 bytes (only the header)
+		23, 3, 3, 0x05, 0x64,
+	}
+	buffer := &bytes.Buffer{}
+	logged := HexToWriter(&MockBody{contents: stream}, buffer, "R [%v]\n", "W [%v]\n", "E [%v: %v]\n", "C\n")
+	logged.TLSRecordAware = true
+	logged.SuppressEOF = true
+
+	// Split the second header across reads.
+	logged.Read(make([]byte, 11))
+	logged.Read(make([]byte, 11))
+	expectBufferContents(t, buffer, ""+
+		"R [16 03 01 00 04 01 02 03 04 17 03]\n"+
+		"LoggedIO: TLS handshake len=4 version=3.1\n"+
+		"R [03 05 64]\n"+
+		"LoggedIO: TLS application_data len=1380 version=3.3\n")
+}
+
+func TestTLSRecordAwareNotTLS(t *testing.T) {
+	buffer := &bytes.Buffer{}
+	logged := StringToWriter(&MockBody{contents: []byte("GET / HTTP/1.1\r\n")}, buffer, "R [%v]\n", "", "", "")
+	logged.TLSRecordAware = true
+	logged.SuppressEOF = true
+
+	logged.Read(make([]byte, 5))
+	logged.Read(make([]byte, 5))
+	expectBufferContents(t, buffer, ""+
+		"R [GET /]\n"+
+		"LoggedIO: TLS: Not a TLS record header: 47 45 54 20 2f\n"+
+		"R [ HTTP]\n")
+}
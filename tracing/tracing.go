@@ -0,0 +1,78 @@
+// Package tracing records a logged I/O proxy's activity as a tracing span, for
+// distributed tracing systems such as OpenTelemetry.
+//
+// To keep the core loggedio package free of tracing dependencies, this package
+// works with the minimal Tracer and Span interfaces below rather than with a
+// specific tracing library. An OpenTelemetry adapter only takes a few lines:
+//
+//	type otelTracer struct{ tracer trace.Tracer }
+//
+//	func (this otelTracer) Start(name string) tracing.Span {
+//	    _, span := this.tracer.Start(context.Background(), name)
+//	    return otelSpan{span}
+//	}
+//
+//	type otelSpan struct{ span trace.Span }
+//
+//	func (this otelSpan) AddEvent(name string, attributes map[string]int64) {
+//	    var kvs []attribute.KeyValue
+//	    for k, v := range attributes {
+//	        kvs = append(kvs, attribute.Int64(k, v))
+//	    }
+//	    this.span.AddEvent(name, trace.WithAttributes(kvs...))
+//	}
+//
+//	// ... and likewise for SetAttributes, RecordError, and End.
+package tracing
+
+import (
+	"github.com/kstenerud/go-loggedio"
+)
+
+// Tracer starts spans.
+type Tracer interface {
+	Start(name string) Span
+}
+
+// Span is a single traced operation.
+type Span interface {
+	AddEvent(name string, attributes map[string]int64)
+	SetAttributes(attributes map[string]int64)
+	RecordError(err error)
+	End()
+}
+
+// Trace starts a span named spanName for the connection behind proxy, and
+// records the proxy's subsequent activity to it:
+//
+//   - Each read or write adds a "read" or "write" event with a "bytes"
+//     attribute.
+//   - Each error is recorded via RecordError().
+//   - On the first close, the "bytes_read", "bytes_written" and "errors"
+//     totals are set as span attributes, and the span is ended.
+//
+// The proxy's existing reporting is unaffected. The span is returned so that
+// further attributes can be added.
+func Trace(proxy *loggedio.LoggedIOProxy, tracer Tracer, spanName string) Span {
+	span := tracer.Start(spanName)
+	proxy.AddSink(loggedio.SinkFunc(func(event *loggedio.Event) {
+		switch event.Type {
+		case loggedio.EventRead:
+			span.AddEvent("read", map[string]int64{"bytes": int64(len(event.Data))})
+		case loggedio.EventWrite:
+			span.AddEvent("write", map[string]int64{"bytes": int64(len(event.Data))})
+		case loggedio.EventError:
+			span.RecordError(event.Err)
+		}
+	}))
+	proxy.OnClose(func(err error) {
+		stats := proxy.Stats()
+		span.SetAttributes(map[string]int64{
+			"bytes_read":    stats.BytesRead,
+			"bytes_written": stats.BytesWritten,
+			"errors":        stats.Errors,
+		})
+		span.End()
+	})
+	return span
+}
@@ -0,0 +1,91 @@
+package tracing
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"testing"
+
+	"github.com/kstenerud/go-loggedio"
+)
+
+type MockTracer struct {
+	spans []*MockSpan
+}
+
+func (this *MockTracer) Start(name string) Span {
+	span := &MockSpan{name: name, attributes: map[string]int64{}}
+	this.spans = append(this.spans, span)
+	return span
+}
+
+type MockSpan struct {
+	name       string
+	events     []string
+	attributes map[string]int64
+	errors     []error
+	ended      bool
+}
+
+func (this *MockSpan) AddEvent(name string, attributes map[string]int64) {
+	this.events = append(this.events, fmt.Sprintf("%v %v", name, attributes["bytes"]))
+}
+
+func (this *MockSpan) SetAttributes(attributes map[string]int64) {
+	for k, v := range attributes {
+		this.attributes[k] = v
+	}
+}
+
+func (this *MockSpan) RecordError(err error) {
+	this.errors = append(this.errors, err)
+}
+
+func (this *MockSpan) End() {
+	this.ended = true
+}
+
+type MockConn struct {
+	bytes.Buffer
+}
+
+func (this *MockConn) Close() error {
+	return io.ErrClosedPipe
+}
+
+func TestTrace(t *testing.T) {
+	conn := &MockConn{}
+	conn.WriteString("hello")
+	buffer := &bytes.Buffer{}
+	proxy := loggedio.StringToWriter(conn, buffer, "R [%v]", "W [%v]", "E [%v: %v]", "C")
+	tracer := &MockTracer{}
+	Trace(proxy, tracer, "conn")
+
+	proxy.Read(make([]byte, 5))
+	proxy.Write([]byte("abc"))
+	proxy.Close()
+
+	if len(tracer.spans) != 1 {
+		t.Fatalf("Expected 1 span but got %v", len(tracer.spans))
+	}
+	span := tracer.spans[0]
+	if span.name != "conn" {
+		t.Errorf("Expected span name conn but got %v", span.name)
+	}
+	if fmt.Sprint(span.events) != "[read 5 write 3]" {
+		t.Errorf("Unexpected span events %v", span.events)
+	}
+	if len(span.errors) != 1 || span.errors[0] != io.ErrClosedPipe {
+		t.Errorf("Expected the close error to be recorded but got %v", span.errors)
+	}
+	if span.attributes["bytes_read"] != 5 || span.attributes["bytes_written"] != 3 ||
+		span.attributes["errors"] != 1 {
+		t.Errorf("Unexpected span attributes %v", span.attributes)
+	}
+	if !span.ended {
+		t.Errorf("Expected span to be ended on close")
+	}
+	if buffer.String() != "R [hello]W [abc]CE [Close(): io: read/write on closed pipe]" {
+		t.Errorf("Expected normal reporting to be unaffected but got %v", buffer.String())
+	}
+}
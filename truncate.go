@@ -0,0 +1,40 @@
+package loggedio
+
+import (
+	"fmt"
+)
+
+// TruncateMode determines which part of a payload is kept when it's longer
+// than MaxLogBytes.
+type TruncateMode int
+
+const (
+	// Keep the first MaxLogBytes bytes (for example "abcdef… (+14 omitted)").
+	TruncateHead TruncateMode = iota
+	// Keep the last MaxLogBytes bytes (for example "…uvwxyz (+14 omitted)").
+	TruncateTail
+	// Keep the first and last MaxLogBytes/2 bytes, so that trailing framing
+	// and checksums remain visible (for example "abc…xyz (+14 omitted)"). The
+	// head gets the extra byte when MaxLogBytes is odd.
+	TruncateMiddle
+)
+
+// truncatePayload renders b using encode, keeping at most maxBytes bytes of it
+// as selected by mode.
+func truncatePayload(encode func([]byte) string, b []byte, maxBytes int, mode TruncateMode) string {
+	if maxBytes <= 0 || len(b) <= maxBytes {
+		return encode(b)
+	}
+	omitted := len(b) - maxBytes
+	switch mode {
+	case TruncateTail:
+		return fmt.Sprintf("…%v (+%v omitted)", encode(b[omitted:]), omitted)
+	case TruncateMiddle:
+		headLength := maxBytes - maxBytes/2
+		head := encode(b[:headLength])
+		tail := encode(b[headLength+omitted:])
+		return fmt.Sprintf("%v…%v (+%v omitted)", head, tail, omitted)
+	default:
+		return fmt.Sprintf("%v… (+%v omitted)", encode(b[:maxBytes]), omitted)
+	}
+}
@@ -0,0 +1,45 @@
+package loggedio
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestTruncateModes(t *testing.T) {
+	payload := []byte("abcdefghijklmnopqrst")
+	for _, test := range []struct {
+		mode     TruncateMode
+		expected string
+	}{
+		{TruncateHead, "W [abcdef… (+14 omitted)]"},
+		{TruncateTail, "W […opqrst (+14 omitted)]"},
+		{TruncateMiddle, "W [abc…rst (+14 omitted)]"},
+	} {
+		buffer := &bytes.Buffer{}
+		logged := StringToWriter(&MockIO{}, buffer, "R [%v]", "W [%v]", "E [%v: %v]", "C")
+		logged.MaxLogBytes = 6
+		logged.TruncateMode = test.mode
+		n, err := logged.Write(payload)
+		expectNoError(t, err)
+		expectNumber(t, len(payload), n)
+		expectBufferContents(t, buffer, test.expected)
+	}
+}
+
+func TestTruncateMiddleHex(t *testing.T) {
+	buffer := &bytes.Buffer{}
+	logged := HexToWriter(&MockIO{}, buffer, "R [%v]", "W [%v]", "E [%v: %v]", "C")
+	logged.MaxLogBytes = 5
+	logged.TruncateMode = TruncateMiddle
+	logged.Write([]byte{1, 2, 3, 4, 5, 6, 7, 8})
+	expectBufferContents(t, buffer, "W [01 02 03…07 08 (+3 omitted)]")
+}
+
+func TestTruncateShortPayload(t *testing.T) {
+	buffer := &bytes.Buffer{}
+	logged := StringToWriter(&MockIO{}, buffer, "R [%v]", "W [%v]", "E [%v: %v]", "C")
+	logged.MaxLogBytes = 6
+	logged.TruncateMode = TruncateMiddle
+	logged.Write([]byte("abcdef"))
+	expectBufferContents(t, buffer, "W [abcdef]")
+}
@@ -0,0 +1,140 @@
+package loggedio
+
+import (
+	"io"
+	"net"
+	"time"
+)
+
+// TypedProxy is a compile-time safe alternative to using *LoggedIOProxy
+// directly. Rather than relying on duck typing, the typed constructors only
+// accept objects that implement the required interface, and the returned
+// proxies only expose the methods of that interface. For example, this fails to
+// compile because *bytes.Reader has no Write() method:
+//
+//	proxy := loggedio.NewTypedReadWriteCloser(bytes.NewReader(data), generate)
+//
+// The untyped proxy can still be reached via Proxy() for access to its options
+// and statistics.
+type TypedProxy[T any] struct {
+	proxy      *LoggedIOProxy
+	underlying T
+}
+
+// NewTyped creates a typed proxy around obj using the proxy built by generate.
+// It exposes no I/O methods of its own; use one of the interface-constrained
+// constructors (NewTypedReader, NewTypedReadWriter, NewTypedReadWriteCloser,
+// NewTypedConn) for that.
+func NewTyped[T any](obj T, generate ProxyGenerator) *TypedProxy[T] {
+	return &TypedProxy[T]{
+		proxy:      generate(obj),
+		underlying: obj,
+	}
+}
+
+// Underlying returns the proxied object.
+func (this *TypedProxy[T]) Underlying() T {
+	return this.underlying
+}
+
+// Proxy returns the untyped logged I/O proxy.
+func (this *TypedProxy[T]) Proxy() *LoggedIOProxy {
+	return this.proxy
+}
+
+// TypedReader is a typed proxy exposing only io.Reader.
+type TypedReader[T io.Reader] struct {
+	TypedProxy[T]
+}
+
+// NewTypedReader creates a typed proxy exposing only io.Reader.
+func NewTypedReader[T io.Reader](obj T, generate ProxyGenerator) *TypedReader[T] {
+	return &TypedReader[T]{*NewTyped(obj, generate)}
+}
+
+func (this *TypedReader[T]) Read(b []byte) (n int, err error) {
+	return this.proxy.Read(b)
+}
+
+// TypedReadWriter is a typed proxy exposing only io.ReadWriter.
+type TypedReadWriter[T io.ReadWriter] struct {
+	TypedProxy[T]
+}
+
+// NewTypedReadWriter creates a typed proxy exposing only io.ReadWriter.
+func NewTypedReadWriter[T io.ReadWriter](obj T, generate ProxyGenerator) *TypedReadWriter[T] {
+	return &TypedReadWriter[T]{*NewTyped(obj, generate)}
+}
+
+func (this *TypedReadWriter[T]) Read(b []byte) (n int, err error) {
+	return this.proxy.Read(b)
+}
+
+func (this *TypedReadWriter[T]) Write(b []byte) (n int, err error) {
+	return this.proxy.Write(b)
+}
+
+// TypedReadWriteCloser is a typed proxy exposing only io.ReadWriteCloser.
+type TypedReadWriteCloser[T io.ReadWriteCloser] struct {
+	TypedProxy[T]
+}
+
+// NewTypedReadWriteCloser creates a typed proxy exposing only
+// io.ReadWriteCloser.
+func NewTypedReadWriteCloser[T io.ReadWriteCloser](obj T, generate ProxyGenerator) *TypedReadWriteCloser[T] {
+	return &TypedReadWriteCloser[T]{*NewTyped(obj, generate)}
+}
+
+func (this *TypedReadWriteCloser[T]) Read(b []byte) (n int, err error) {
+	return this.proxy.Read(b)
+}
+
+func (this *TypedReadWriteCloser[T]) Write(b []byte) (n int, err error) {
+	return this.proxy.Write(b)
+}
+
+func (this *TypedReadWriteCloser[T]) Close() error {
+	return this.proxy.Close()
+}
+
+// TypedConn is a typed proxy exposing net.Conn.
+type TypedConn[T net.Conn] struct {
+	TypedProxy[T]
+}
+
+// NewTypedConn creates a typed proxy exposing net.Conn.
+func NewTypedConn[T net.Conn](obj T, generate ProxyGenerator) *TypedConn[T] {
+	return &TypedConn[T]{*NewTyped(obj, generate)}
+}
+
+func (this *TypedConn[T]) Read(b []byte) (n int, err error) {
+	return this.proxy.Read(b)
+}
+
+func (this *TypedConn[T]) Write(b []byte) (n int, err error) {
+	return this.proxy.Write(b)
+}
+
+func (this *TypedConn[T]) Close() error {
+	return this.proxy.Close()
+}
+
+func (this *TypedConn[T]) LocalAddr() net.Addr {
+	return this.proxy.LocalAddr()
+}
+
+func (this *TypedConn[T]) RemoteAddr() net.Addr {
+	return this.proxy.RemoteAddr()
+}
+
+func (this *TypedConn[T]) SetDeadline(t time.Time) error {
+	return this.proxy.SetDeadline(t)
+}
+
+func (this *TypedConn[T]) SetReadDeadline(t time.Time) error {
+	return this.proxy.SetReadDeadline(t)
+}
+
+func (this *TypedConn[T]) SetWriteDeadline(t time.Time) error {
+	return this.proxy.SetWriteDeadline(t)
+}
@@ -0,0 +1,60 @@
+package loggedio
+
+import (
+	"bytes"
+	"io"
+	"net"
+	"testing"
+	"time"
+)
+
+func newBufferGenerator(buffer *bytes.Buffer) ProxyGenerator {
+	return func(o interface{}) *LoggedIOProxy {
+		return StringToWriter(o, buffer, "R [%v]", "W [%v]", "E [%v: %v]", "C")
+	}
+}
+
+func TestTypedReader(t *testing.T) {
+	buffer := &bytes.Buffer{}
+	reader := &MockReader{implementation: &MockIO{}}
+	typed := NewTypedReader(reader, newBufferGenerator(buffer))
+
+	var _ io.Reader = typed
+	if _, isWriter := interface{}(typed).(io.Writer); isWriter {
+		t.Errorf("Expected TypedReader to not implement io.Writer")
+	}
+	if typed.Underlying() != reader {
+		t.Errorf("Expected Underlying() to return the reader")
+	}
+	typed.Read(make([]byte, 3))
+	expectBufferContents(t, buffer, "R [abc]")
+}
+
+func TestTypedReadWriteCloser(t *testing.T) {
+	buffer := &bytes.Buffer{}
+	proxied := &MockIO{}
+	typed := NewTypedReadWriteCloser(proxied, newBufferGenerator(buffer))
+
+	var _ io.ReadWriteCloser = typed
+	if _, isConn := interface{}(typed).(net.Conn); isConn {
+		t.Errorf("Expected TypedReadWriteCloser to not implement net.Conn")
+	}
+	typed.Write([]byte("test"))
+	typed.Close()
+	expectBufferContents(t, buffer, "W [test]C")
+	expectNumber(t, 4, int(typed.Proxy().Stats().BytesWritten))
+}
+
+func TestTypedConn(t *testing.T) {
+	buffer := &bytes.Buffer{}
+	proxied := &MockIO{}
+	typed := NewTypedConn(proxied, newBufferGenerator(buffer))
+
+	var _ net.Conn = typed
+	typed.Read(make([]byte, 3))
+	typed.LocalAddr()
+	typed.SetReadDeadline(time.Now())
+	expectBufferContents(t, buffer, "R [abc]")
+	expectNumber(t, 1, proxied.LocalAddrCallCount)
+	expectNumber(t, 1, proxied.SetReadDeadlineCallCount)
+}
@@ -0,0 +1,110 @@
+package loggedio
+
+import (
+	"encoding/binary"
+	"fmt"
+	"sync"
+)
+
+// The largest WebSocket frame payload that will be buffered for decoding.
+const maxWebSocketFrameLength = 16 * 1024 * 1024
+
+var webSocketOpcodeNames = map[byte]string{
+	0:  "CONTINUATION",
+	1:  "TEXT",
+	2:  "BINARY",
+	8:  "CLOSE",
+	9:  "PING",
+	10: "PONG",
+}
+
+// webSocketFrameDecoder reassembles WebSocket frames from one direction of a
+// stream.
+type webSocketFrameDecoder struct {
+	mutex   sync.Mutex
+	pending []byte
+	// Set once the stream is found not to be decodable.
+	gaveUp bool
+}
+
+// feed consumes stream bytes, and returns an annotation for each frame that
+// they complete. direction is "recv" or "send".
+func (this *webSocketFrameDecoder) feed(direction string, b []byte) (annotations []string) {
+	this.mutex.Lock()
+	defer this.mutex.Unlock()
+	if this.gaveUp {
+		return
+	}
+	this.pending = append(this.pending, b...)
+	for {
+		frame := this.pending
+		if len(frame) < 2 {
+			break
+		}
+		fin := frame[0] >> 7
+		opcode := frame[0] & 0x0f
+		masked := frame[1]&0x80 != 0
+		headerLength := 2
+		length := uint64(frame[1] & 0x7f)
+		switch length {
+		case 126:
+			headerLength += 2
+			if len(frame) >= headerLength {
+				length = uint64(binary.BigEndian.Uint16(frame[2:]))
+			}
+		case 127:
+			headerLength += 8
+			if len(frame) >= headerLength {
+				length = binary.BigEndian.Uint64(frame[2:])
+			}
+		}
+		if masked {
+			headerLength += 4
+		}
+		if len(frame) < headerLength {
+			break
+		}
+		if length > maxWebSocketFrameLength {
+			annotations = append(annotations,
+				fmt.Sprintf("LoggedIO: WebSocket: Frame too large to decode (len=%v)\n", length))
+			this.gaveUp = true
+			this.pending = nil
+			break
+		}
+		frameLength := headerLength + int(length)
+		if len(frame) < frameLength {
+			break
+		}
+
+		payload := append([]byte(nil), frame[headerLength:frameLength]...)
+		maskDescription := ""
+		if masked {
+			maskKey := frame[headerLength-4 : headerLength]
+			for i := range payload {
+				payload[i] ^= maskKey[i%4]
+			}
+			maskDescription = " masked"
+		}
+		annotations = append(annotations, fmt.Sprintf("LoggedIO: %v %v fin=%v%v len=%v %v\n",
+			direction, webSocketOpcodeName(opcode), fin, maskDescription, length,
+			describeWebSocketPayload(opcode, payload)))
+		this.pending = append(this.pending[:0], frame[frameLength:]...)
+	}
+	return
+}
+
+func webSocketOpcodeName(opcode byte) string {
+	if name, ok := webSocketOpcodeNames[opcode]; ok {
+		return name
+	}
+	return fmt.Sprintf("OPCODE(%v)", opcode)
+}
+
+// describeWebSocketPayload renders text payloads as quoted strings, and all
+// others as hex.
+func describeWebSocketPayload(opcode byte, payload []byte) string {
+	if opcode == 1 {
+		return fmt.Sprintf("%q", payload)
+	}
+	return "[" + toHex(payload) + "]"
+}
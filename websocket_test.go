@@ -0,0 +1,48 @@
+package loggedio
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestWebSocketAware(t *testing.T) {
+	frame := append([]byte{0x81, 12}, "hello world!"...)
+	buffer := &bytes.Buffer{}
+	logged := HexToWriter(&MockBody{contents: frame}, buffer, "", "", "E [%v: %v]\n", "C\n")
+	logged.WebSocketAware = true
+	logged.SuppressEOF = true
+
+	// Split the frame across reads.
+	logged.Read(make([]byte, 5))
+	expectBufferContents(t, buffer, "")
+	logged.Read(make([]byte, 20))
+	expectBufferContents(t, buffer, "LoggedIO: recv TEXT fin=1 len=12 \"hello world!\"\n")
+}
+
+func TestWebSocketAwareMaskedWrite(t *testing.T) {
+	maskKey := []byte{1, 2, 3, 4}
+	frame := []byte{0x82, 0x80 | 3}
+	frame = append(frame, maskKey...)
+	for i, ch := range []byte{0xa0, 0xb0, 0xc0} {
+		frame = append(frame, ch^maskKey[i%4])
+	}
+	// A second, extended length frame in the same write, with FIN unset.
+	frame = append(frame, 0x01, 126, 0, 2, 'h', 'i')
+
+	buffer := &bytes.Buffer{}
+	logged := HexToWriter(&MockIO{}, buffer, "", "W [%v]\n", "", "")
+	logged.WebSocketAware = true
+	logged.Write(frame)
+	expectBufferContents(t, buffer, "W ["+toHex(frame)+"]\n"+
+		"LoggedIO: send BINARY fin=1 masked len=3 [a0 b0 c0]\n"+
+		"LoggedIO: send TEXT fin=0 len=2 \"hi\"\n")
+}
+
+func TestWebSocketAwareFrameTooLarge(t *testing.T) {
+	buffer := &bytes.Buffer{}
+	logged := HexToWriter(&MockIO{}, buffer, "", "", "", "")
+	logged.WebSocketAware = true
+	logged.Write([]byte{0x82, 127, 0, 0, 0, 0, 0x10, 0, 0, 0})
+	logged.Write([]byte{0x81, 1, 'a'})
+	expectBufferContents(t, buffer, "LoggedIO: WebSocket: Frame too large to decode (len=268435456)\n")
+}
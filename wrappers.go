@@ -0,0 +1,139 @@
+package loggedio
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net"
+)
+
+// ProxyGenerator creates a logged I/O proxy around an object. It's usually a
+// closure over one of the proxy generators in this package, for example:
+//
+//	func(o interface{}) *LoggedIOProxy {
+//	    return StringToLog(o, "R [%v]", "W [%v]", "E [%v: %v]", "C")
+//	}
+type ProxyGenerator func(proxiedObject interface{}) *LoggedIOProxy
+
+// NewReadCloser wraps an io.ReadCloser (such as http.Response.Body) in a
+// logged I/O proxy built by generate. The io.EOF that ends the stream is not
+// reported as an error, and only the first Close() is reported.
+//
+// The returned io.ReadCloser can be cast to *LoggedIOProxy if needed.
+func NewReadCloser(rc io.ReadCloser, generate ProxyGenerator) io.ReadCloser {
+	proxy := generate(rc)
+	proxy.SuppressEOF = true
+	proxy.reportFirstCloseOnly = true
+	return proxy
+}
+
+// NewReadWriter wraps an io.ReadWriter (such as a bytes.Buffer or one end of
+// an io.Pipe) in a logged I/O proxy built by generate. Unlike the proxy
+// itself, the returned io.ReadWriter only has Read() and Write() methods, so
+// it can't be mistaken for an io.Closer or a net.Conn. It can be cast to
+// *TypedReadWriter[io.ReadWriter] to reach the proxy if needed.
+func NewReadWriter(rw io.ReadWriter, generate ProxyGenerator) io.ReadWriter {
+	return NewTypedReadWriter(rw, generate)
+}
+
+// Stack creates two logged I/O proxies around proxiedObject: an inner proxy
+// built by generateInner that wraps proxiedObject directly, and an outer proxy
+// built by generateOuter that wraps the inner proxy. The outer proxy is
+// returned, and the inner proxy can be reached via its Unwrap() method.
+//
+// Every event is reported by both proxies, but bytes and errors are only
+// counted once: the outer proxy's Stats() returns the inner proxy's totals.
+func Stack(proxiedObject interface{}, generateOuter, generateInner ProxyGenerator) *LoggedIOProxy {
+	inner := generateInner(proxiedObject)
+	outer := generateOuter(inner)
+	outer.statsDelegate = inner
+	return outer
+}
+
+// NewLimitedReader wraps r in a logged I/O proxy built by generate, and caps
+// the total number of bytes that can be read from it in the same way as
+// io.LimitReader: once limit bytes have been read, Read() returns io.EOF.
+// When the limit is reached, a notification "LoggedIO: Read limit of <limit>
+// bytes reached" is reported once, so that truncation can be detected.
+func NewLimitedReader(r io.Reader, limit int64, generate ProxyGenerator) io.Reader {
+	return &limitedReader{
+		proxy:     generate(r),
+		limit:     limit,
+		remaining: limit,
+	}
+}
+
+type limitedReader struct {
+	proxy           *LoggedIOProxy
+	limit           int64
+	remaining       int64
+	isLimitReported bool
+}
+
+func (this *limitedReader) Read(b []byte) (n int, err error) {
+	if this.remaining <= 0 {
+		this.reportLimitReached()
+		return 0, io.EOF
+	}
+	if int64(len(b)) > this.remaining {
+		b = b[:this.remaining]
+	}
+	n, err = this.proxy.Read(b)
+	this.remaining -= int64(n)
+	if this.remaining == 0 {
+		this.reportLimitReached()
+	}
+	return
+}
+
+func (this *limitedReader) reportLimitReached() {
+	if !this.isLimitReported {
+		this.isLimitReported = true
+		this.proxy.reportNotify(fmt.Sprintf("LoggedIO: Read limit of %v bytes reached\n", this.limit))
+	}
+}
+
+// LoggedPipe creates a synchronous in-memory connection via net.Pipe(), and
+// wraps each end in a logged I/O proxy built by generate, for full traffic logs
+// in in-process client/server tests. generate is called with the label "A" for
+// the first end and "B" for the second, so that each end's reports can be
+// told apart.
+func LoggedPipe(generate func(label string, proxiedObject interface{}) *LoggedIOProxy) (a, b net.Conn) {
+	rawA, rawB := net.Pipe()
+	return generate("A", rawA), generate("B", rawB)
+}
+
+// DialContextFunc has the signature of net.Dialer.DialContext(), and of the
+// DialContext field of http.Transport.
+type DialContextFunc func(ctx context.Context, network, address string) (net.Conn, error)
+
+// LoggingDialContext wraps a dial function so that every connection it dials
+// is wrapped in a logged I/O proxy built by configure. If base is nil, a zero
+// net.Dialer is used. For example, to log the raw bytes of HTTP/1.1 requests
+// and responses:
+//
+//	transport := &http.Transport{
+//		DialContext: loggedio.LoggingDialContext(nil, func(conn net.Conn) *loggedio.LoggedIOProxy {
+//			return loggedio.StringToLog(conn, "R [%v]", "W [%v]", "E [%v: %v]", "C")
+//		}),
+//	}
+//
+// If configure returns nil, the dialed connection is closed and an error is
+// returned.
+func LoggingDialContext(base DialContextFunc, configure func(conn net.Conn) *LoggedIOProxy) DialContextFunc {
+	if base == nil {
+		base = (&net.Dialer{}).DialContext
+	}
+	return func(ctx context.Context, network, address string) (net.Conn, error) {
+		conn, err := base(ctx, network, address)
+		if err != nil {
+			return nil, err
+		}
+		proxy := configure(conn)
+		if proxy == nil {
+			conn.Close()
+			return nil, fmt.Errorf("LoggedIO: no proxy was configured for the connection to %v", address)
+		}
+		return proxy, nil
+	}
+}
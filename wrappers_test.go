@@ -0,0 +1,225 @@
+package loggedio
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"io/ioutil"
+	"net"
+	"testing"
+)
+
+// MockBody behaves like an http.Response.Body, returning the last of its data
+// together with io.EOF.
+type MockBody struct {
+	contents       []byte
+	CloseCallCount int
+}
+
+func (this *MockBody) Read(b []byte) (n int, err error) {
+	n = copy(b, this.contents)
+	this.contents = this.contents[n:]
+	if len(this.contents) == 0 {
+		err = io.EOF
+	}
+	return
+}
+
+func (this *MockBody) Close() (err error) {
+	this.CloseCallCount++
+	return
+}
+
+func TestNewReadCloser(t *testing.T) {
+	body := &MockBody{contents: []byte("hello")}
+	buffer := &bytes.Buffer{}
+	rc := NewReadCloser(body, func(o interface{}) *LoggedIOProxy {
+		return StringToWriter(o, buffer, "R [%v]", "W [%v]", "E [%v: %v]", "C")
+	})
+
+	contents, err := ioutil.ReadAll(rc)
+	expectNoError(t, err)
+	expectLength(t, contents, 5)
+	expectBufferContents(t, buffer, "R [hello]")
+
+	buffer.Reset()
+	expectNoError(t, rc.Close())
+	expectNoError(t, rc.Close())
+	expectBufferContents(t, buffer, "C")
+	expectNumber(t, 2, body.CloseCallCount)
+}
+
+func TestNewReadWriter(t *testing.T) {
+	proxied := &bytes.Buffer{}
+	buffer := &bytes.Buffer{}
+	rw := NewReadWriter(proxied, func(o interface{}) *LoggedIOProxy {
+		return StringToWriter(o, buffer, "R [%v]", "W [%v]", "E [%v: %v]", "C")
+	})
+	if _, ok := rw.(io.Closer); ok {
+		t.Errorf("Expected the read writer not to implement io.Closer")
+	}
+	if _, ok := rw.(net.Conn); ok {
+		t.Errorf("Expected the read writer not to implement net.Conn")
+	}
+
+	_, err := rw.Write([]byte("test"))
+	expectNoError(t, err)
+	b := make([]byte, 10)
+	n, err := rw.Read(b)
+	expectNoError(t, err)
+	expectString(t, "test", string(b[:n]))
+	expectBufferContents(t, buffer, "W [test]R [test]")
+	expectNumber(t, 4, int(rw.(*TypedReadWriter[io.ReadWriter]).Proxy().Stats().BytesRead))
+}
+
+func TestCloseReportedEachCall(t *testing.T) {
+	proxied := &MockIO{}
+	buffer := &bytes.Buffer{}
+	logged := StringToWriter(proxied, buffer, "R [%v]", "W [%v]", "E [%v: %v]", "C")
+	expectNoError(t, logged.Close())
+	expectNoError(t, logged.Close())
+	expectBufferContents(t, buffer, "CC")
+}
+
+func TestNewLimitedReader(t *testing.T) {
+	body := &MockBody{contents: []byte("hello world")}
+	buffer := &bytes.Buffer{}
+	r := NewLimitedReader(body, 7, func(o interface{}) *LoggedIOProxy {
+		return StringToWriter(o, buffer, "R [%v]", "W [%v]", "E [%v: %v]", "C")
+	})
+
+	b := make([]byte, 5)
+	n, err := r.Read(b)
+	expectNoError(t, err)
+	expectNumber(t, 5, n)
+	n, err = r.Read(b)
+	expectNoError(t, err)
+	expectNumber(t, 2, n)
+	n, err = r.Read(b)
+	expectNumber(t, 0, n)
+	if err != io.EOF {
+		t.Errorf("Expected io.EOF but got %v", err)
+	}
+	expectBufferContents(t, buffer,
+		"R [hello]R [ w]LoggedIO: Read limit of 7 bytes reached\n")
+}
+
+func TestNewLimitedReaderZeroLimit(t *testing.T) {
+	buffer := &bytes.Buffer{}
+	r := NewLimitedReader(&MockBody{contents: []byte("hello")}, 0, func(o interface{}) *LoggedIOProxy {
+		return StringToWriter(o, buffer, "R [%v]", "W [%v]", "E [%v: %v]", "C")
+	})
+
+	for i := 0; i < 2; i++ {
+		n, err := r.Read(make([]byte, 5))
+		expectNumber(t, 0, n)
+		if err != io.EOF {
+			t.Errorf("Expected io.EOF but got %v", err)
+		}
+	}
+	expectBufferContents(t, buffer, "LoggedIO: Read limit of 0 bytes reached\n")
+}
+
+func TestStack(t *testing.T) {
+	proxied := &MockIO{}
+	outerBuffer := &bytes.Buffer{}
+	innerBuffer := &bytes.Buffer{}
+	outer := Stack(proxied,
+		func(o interface{}) *LoggedIOProxy {
+			return HexToWriter(o, outerBuffer, "R [%v]", "W [%v]", "E [%v: %v]", "C")
+		},
+		func(o interface{}) *LoggedIOProxy {
+			return StringToWriter(o, innerBuffer, "R [%v]", "W [%v]", "E [%v: %v]", "C")
+		})
+
+	_, err := outer.Write([]byte("test"))
+	expectNoError(t, err)
+	expectBufferContents(t, outerBuffer, "W [74 65 73 74]")
+	expectBufferContents(t, innerBuffer, "W [test]")
+
+	inner, ok := outer.Unwrap().(*LoggedIOProxy)
+	if !ok {
+		t.Fatalf("Expected Unwrap() to return the inner proxy")
+	}
+	if inner.Unwrap() != proxied {
+		t.Errorf("Expected inner proxy to wrap the proxied object")
+	}
+	expectNumber(t, 4, int(outer.Stats().BytesWritten))
+	expectNumber(t, 4, int(inner.Stats().BytesWritten))
+
+	outer.LocalAddr()
+	expectNumber(t, 1, proxied.LocalAddrCallCount)
+}
+
+func TestLoggedPipe(t *testing.T) {
+	buffers := map[string]*SyncBuffer{"A": {}, "B": {}}
+	a, b := LoggedPipe(func(label string, o interface{}) *LoggedIOProxy {
+		return StringToWriter(o, buffers[label],
+			label+" R [%v]", label+" W [%v]", label+" E [%v: %v]", label+" C")
+	})
+
+	done := make(chan bool)
+	go func() {
+		a.Write([]byte("hello"))
+		a.Close()
+		close(done)
+	}()
+	contents, err := ioutil.ReadAll(b)
+	expectNoError(t, err)
+	expectString(t, "hello", string(contents))
+	b.Close()
+	<-done
+
+	expectString(t, "A W [hello]A C", buffers["A"].String())
+	expectString(t, "B R [hello]B E [Read(): EOF]B C", buffers["B"].String())
+}
+
+func TestLoggingDialContext(t *testing.T) {
+	buffer := &bytes.Buffer{}
+	var dialedAddress string
+	dial := LoggingDialContext(func(ctx context.Context, network, address string) (net.Conn, error) {
+		dialedAddress = address
+		return &MockIO{}, nil
+	}, func(conn net.Conn) *LoggedIOProxy {
+		return StringToWriter(conn, buffer, "R [%v]", "W [%v]", "E [%v: %v]", "C")
+	})
+
+	conn, err := dial(context.Background(), "tcp", "example.com:80")
+	expectNoError(t, err)
+	expectString(t, "example.com:80", dialedAddress)
+	if _, ok := conn.(*LoggedIOProxy); !ok {
+		t.Fatalf("Expected a *LoggedIOProxy but got %T", conn)
+	}
+	conn.Write([]byte("GET / HTTP/1.1"))
+	expectBufferContents(t, buffer, "W [GET / HTTP/1.1]")
+}
+
+func TestLoggingDialContextError(t *testing.T) {
+	dialErr := errors.New("refused")
+	dial := LoggingDialContext(func(ctx context.Context, network, address string) (net.Conn, error) {
+		return nil, dialErr
+	}, func(conn net.Conn) *LoggedIOProxy {
+		t.Fatalf("configure should not be called on a failed dial")
+		return nil
+	})
+	conn, err := dial(context.Background(), "tcp", "example.com:80")
+	if conn != nil || err != dialErr {
+		t.Errorf("Expected nil, %v but got %v, %v", dialErr, conn, err)
+	}
+}
+
+func TestLoggingDialContextNoProxy(t *testing.T) {
+	dialed := &MockIO{}
+	dial := LoggingDialContext(func(ctx context.Context, network, address string) (net.Conn, error) {
+		return dialed, nil
+	}, func(conn net.Conn) *LoggedIOProxy {
+		return nil
+	})
+	conn, err := dial(context.Background(), "tcp", "example.com:80")
+	expectError(t, err)
+	if conn != nil {
+		t.Errorf("Expected a nil conn but got %T", conn)
+	}
+	expectNumber(t, 1, dialed.CloseCallCount)
+}
@@ -0,0 +1,60 @@
+package loggedio
+
+import (
+	"io"
+	"sync"
+)
+
+// Record describes where a single read or write payload was stored by
+// DumpToWriterAt().
+type Record struct {
+	Offset    int64
+	Length    int
+	Direction EventType // EventRead or EventWrite
+}
+
+// DumpIndex holds the records of all payloads stored by DumpToWriterAt().
+type DumpIndex struct {
+	mutex   sync.Mutex
+	records []Record
+}
+
+// Index returns a copy of all records so far, in the order they were written.
+func (this *DumpIndex) Index() []Record {
+	this.mutex.Lock()
+	defer this.mutex.Unlock()
+	return append([]Record(nil), this.records...)
+}
+
+// DumpToWriterAt creates a logged I/O proxy that writes every read and write
+// payload to w at a monotonically advancing offset (starting at 0), recording
+// each payload's offset, length, and direction in the returned index. Together
+// they can be used to build a capture file with random access.
+//
+// A failure to write to w is reported as an error event with the location
+// "LoggedIO writerAt", and the failed payload is not indexed.
+func DumpToWriterAt(proxiedObject interface{}, w io.WriterAt) (*LoggedIOProxy, *DumpIndex) {
+	index := &DumpIndex{}
+	var offset int64
+	var this *LoggedIOProxy
+	this = newProxy(proxiedObject, func(event *Event) {
+		if event.Type != EventRead && event.Type != EventWrite {
+			return
+		}
+		index.mutex.Lock()
+		n, err := w.WriteAt(event.Data, offset)
+		if err == nil {
+			index.records = append(index.records, Record{
+				Offset:    offset,
+				Length:    n,
+				Direction: event.Type,
+			})
+		}
+		offset += int64(n)
+		index.mutex.Unlock()
+		if err != nil {
+			this.reportError("LoggedIO writerAt", err)
+		}
+	})
+	return this, index
+}
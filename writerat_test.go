@@ -0,0 +1,43 @@
+package loggedio
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+)
+
+func TestDumpToWriterAt(t *testing.T) {
+	file, err := ioutil.TempFile("", "loggedio")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(file.Name())
+	defer file.Close()
+
+	proxied := &MockIO{}
+	logged, index := DumpToWriterAt(proxied, file)
+	logged.Write([]byte("test"))
+	logged.Read(make([]byte, 3))
+
+	records := index.Index()
+	expectNumber(t, 2, len(records))
+	expected := []Record{
+		{Offset: 0, Length: 4, Direction: EventWrite},
+		{Offset: 4, Length: 3, Direction: EventRead},
+	}
+	for i, record := range records {
+		if record != expected[i] {
+			t.Errorf("Expected record %v but got %v", expected[i], record)
+		}
+	}
+
+	payloads := []string{"test", "abc"}
+	for i, record := range records {
+		contents := make([]byte, record.Length)
+		_, err := file.ReadAt(contents, record.Offset)
+		expectNoError(t, err)
+		if string(contents) != payloads[i] {
+			t.Errorf("Expected payload \"%v\" but got \"%v\"", payloads[i], string(contents))
+		}
+	}
+}